@@ -0,0 +1,116 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	storage "cloud.google.com/go/storage"
+	storagetransfer "cloud.google.com/go/storagetransfer/apiv1"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+	cloudrun "google.golang.org/api/run/v1"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// IAM returns the IAM client, creating it on first use
+func (c *Gcp) IAM() (*iam.Service, error) {
+	return lazyClient(c, ServiceIAM, c.IamService, &c.iamClient, iam.NewService)
+}
+
+// SecretManager returns the Secret Manager client, creating it on first use
+func (c *Gcp) SecretManager() (*secretmanager.Client, error) {
+	return lazyClient(c, ServiceSecretManager, c.SecretManagerService, &c.secretManagerClient, secretmanager.NewClient)
+}
+
+// SQL returns the Cloud SQL Admin client, creating it on first use
+func (c *Gcp) SQL() (*sqladmin.Service, error) {
+	return lazyClient(c, ServiceSQL, c.SqlService, &c.sqlClient, sqladmin.NewService)
+}
+
+// StorageTransfer returns the Storage Transfer client, creating it on first use
+func (c *Gcp) StorageTransfer() (*storagetransfer.Client, error) {
+	return lazyClient(c, ServiceStorageTransfer, nil, &c.storageTransferClient, storagetransfer.NewClient)
+}
+
+// CloudRun returns the Cloud Run client, creating it on first use
+func (c *Gcp) CloudRun() (*cloudrun.APIService, error) {
+	return lazyClient(c, ServiceCloudRun, c.CloudRunService, &c.cloudRunClient, cloudrun.NewService)
+}
+
+// Storage returns the Cloud Storage client, creating it on first use
+func (c *Gcp) Storage() (*storage.Client, error) {
+	return lazyClient(c, ServiceStorage, c.StorageService, &c.storageClient, storage.NewClient)
+}
+
+// ResourceManager returns the Resource Manager client used for project IAM policies, creating it on first use
+func (c *Gcp) ResourceManager() (*resourcemanager.Service, error) {
+	return lazyClient(c, ServiceResourceManager, nil, &c.resourceManager, resourcemanager.NewService)
+}
+
+// Close releases the connections of every client created so far. Clients are created again if used afterwards.
+// Clients set through the exported fields are left open.
+func (c *Gcp) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	if c.secretManagerClient != nil {
+		errs = append(errs, c.secretManagerClient.Close())
+	}
+	if c.storageTransferClient != nil {
+		errs = append(errs, c.storageTransferClient.Close())
+	}
+	if c.storageClient != nil {
+		errs = append(errs, c.storageClient.Close())
+	}
+	// The REST based clients hold no connections of their own
+	c.iamClient, c.secretManagerClient, c.sqlClient = nil, nil, nil
+	c.storageTransferClient, c.cloudRunClient, c.storageClient = nil, nil, nil
+	c.resourceManager = nil
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("gcp: unable to close clients: %v", err)
+	}
+	slog.Info("GCP: clients closed", slog.String("Env", c.Env))
+	return nil
+}
+
+// lazyClient returns the injected client when set, otherwise *client, creating it with the service's options the first time
+func lazyClient[T comparable](c *Gcp, service Service, injected T, client *T, create func(context.Context, ...option.ClientOption) (T, error)) (T, error) {
+	var zero T
+	if injected != zero {
+		return injected, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if *client != zero {
+		return *client, nil
+	}
+	if len(c.Services) > 0 && !slices.Contains(c.Services, service) {
+		return zero, fmt.Errorf("gcp: %s service is not enabled", service)
+	}
+	if c.options == nil {
+		return zero, fmt.Errorf("gcp: client is not initialized, call InitGcp first")
+	}
+	created, err := create(context.Background(), c.serviceOptions(service)...)
+	if err != nil {
+		return zero, fmt.Errorf("gcp: unable to create %s service client: %v", service, err)
+	}
+	*client = created
+	slog.Info("GCP: service client created", slog.String("Service", string(service)), slog.String("Env", c.Env))
+	return created, nil
+}
+
+// serviceOptions adds the endpoint override of a service to the shared credential options
+func (c *Gcp) serviceOptions(service Service) []option.ClientOption {
+	if endpoint := c.Credentials.Endpoints[service]; endpoint != "" {
+		return append(c.options[:len(c.options):len(c.options)], option.WithEndpoint(endpoint))
+	}
+	return c.options
+}
@@ -0,0 +1,94 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/api/option"
+	cloudrun "google.golang.org/api/run/v1"
+)
+
+func TestLazyClient(t *testing.T) {
+	gcp := &Gcp{options: []option.ClientOption{option.WithoutAuthentication()}}
+
+	first, err := gcp.CloudRun()
+	if err != nil {
+		t.Fatalf("CloudRun() error = %v", err)
+	}
+	second, err := gcp.CloudRun()
+	if err != nil {
+		t.Fatalf("CloudRun() error = %v", err)
+	}
+	if first != second {
+		t.Error("CloudRun() created a second client")
+	}
+	if gcp.CloudRunService != nil {
+		t.Error("CloudRun() wrote the created client into the exported field")
+	}
+
+	if _, err := (&Gcp{}).CloudRun(); err == nil {
+		t.Error("CloudRun() expected error before InitGcp")
+	}
+}
+
+func TestLazyClientServices(t *testing.T) {
+	gcp := &Gcp{Services: []Service{ServiceCloudRun}, options: []option.ClientOption{option.WithoutAuthentication()}}
+
+	if _, err := gcp.CloudRun(); err != nil {
+		t.Errorf("CloudRun() error = %v", err)
+	}
+	if _, err := gcp.IAM(); err == nil {
+		t.Error("IAM() expected error for a service that isn't enabled")
+	}
+}
+
+func TestLazyClientEndpoint(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	gcp := &Gcp{
+		ProjectID:   "test",
+		Credentials: CredentialOptions{Endpoints: map[Service]string{ServiceCloudRun: server.URL + "/"}},
+		options:     []option.ClientOption{option.WithoutAuthentication()},
+	}
+
+	if _, err := gcp.ListCloudRunRevisions("europe-west1", "web"); err != nil {
+		t.Fatalf("ListCloudRunRevisions() error = %v", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("endpoint override got %d requests, want 1", len(paths))
+	}
+	if len(gcp.options) != 1 {
+		t.Errorf("endpoint override changed the shared options: %d options", len(gcp.options))
+	}
+}
+
+func TestClose(t *testing.T) {
+	injected, err := cloudrun.NewService(context.Background(), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("cloudrun.NewService() error = %v", err)
+	}
+	gcp := &Gcp{CloudRunService: injected, options: []option.ClientOption{option.WithoutAuthentication()}}
+	created, err := gcp.IAM()
+	if err != nil {
+		t.Fatalf("IAM() error = %v", err)
+	}
+
+	if err := gcp.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := gcp.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if client, _ := gcp.CloudRun(); client != injected {
+		t.Error("Close() dropped the injected client")
+	}
+	if client, err := gcp.IAM(); err != nil || client == created {
+		t.Errorf("IAM() after Close() = %p, %v, want a new client", client, err)
+	}
+}
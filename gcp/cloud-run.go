@@ -0,0 +1,367 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	cloudrun "google.golang.org/api/run/v1"
+)
+
+// How often the Cloud Run helpers check whether a service became ready
+const cloudRunPollInterval = 3 * time.Second
+
+// CloudRunLatestRevision stands for the latest ready revision in SetCloudRunTraffic
+const CloudRunLatestRevision = "LATEST"
+
+// CloudRunSecret points an environment variable at a Secret Manager secret
+type CloudRunSecret struct {
+	Secret  string // Secret Manager secret name
+	Version string // Defaults to latest
+}
+
+// CloudRunServiceSpec describes a Cloud Run service deployed by DeployCloudRunService
+type CloudRunServiceSpec struct {
+	Name     string
+	Region   string
+	Image    string
+	Port     int64  // Container port receiving requests, defaults to 8080
+	Revision string // Revision name suffix, Cloud Run generates one when empty
+
+	Env     map[string]string
+	Secrets map[string]CloudRunSecret // Environment variables read from Secret Manager
+
+	CPU          string // CPU limit, e.g. 1 or 500m
+	Memory       string // Memory limit, e.g. 512Mi
+	MinInstances int
+	MaxInstances int   // 0 keeps the Cloud Run default
+	Concurrency  int64 // Requests per instance, 0 keeps the Cloud Run default
+
+	ServiceAccount string // Service account the revision runs as, defaults to the Compute Engine default account
+	NoTraffic      bool   // Keep traffic on the current revisions, e.g. to shift it later with SetCloudRunTraffic
+	CreateOnly     bool   // Fail instead of replacing an existing service
+	NoWait         bool   // Return once the service is created or replaced instead of waiting until it is ready
+}
+
+// DeployCloudRunService creates the service or replaces the spec of an existing one and waits until the new revision
+// is ready, as long as ctx allows. Service labels and annotations the spec doesn't cover, e.g. the ingress setting,
+// are kept when replacing.
+func (gcp *Gcp) DeployCloudRunService(ctx context.Context, spec CloudRunServiceSpec) (*cloudrun.Service, error) {
+	runService, err := gcp.CloudRun()
+	if err != nil {
+		return nil, err
+	}
+	service := spec.service()
+
+	existing, err := runService.Projects.Locations.Services.Get(gcp.cloudRunServicePath(spec.Region, spec.Name)).Context(ctx).Do()
+	var deployed *cloudrun.Service
+	switch {
+	case isNotFound(err):
+		if spec.NoTraffic {
+			return nil, fmt.Errorf("gcp: Cloud Run service %s doesn't exist yet, it can't be deployed without traffic", spec.Name)
+		}
+		parent := fmt.Sprintf("projects/%s/locations/%s", gcp.ProjectID, spec.Region)
+		deployed, err = runService.Projects.Locations.Services.Create(parent, service).Context(ctx).Do()
+	case err != nil:
+		return nil, fmt.Errorf("gcp: unable to get Cloud Run service %s: %v", spec.Name, err)
+	case spec.CreateOnly:
+		return nil, fmt.Errorf("gcp: Cloud Run service %s already exists", spec.Name)
+	default:
+		service.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+		service.Metadata.Labels = existing.Metadata.Labels
+		service.Metadata.Annotations = existing.Metadata.Annotations
+		if spec.NoTraffic {
+			service.Spec.Traffic = pinTraffic(existing)
+		}
+		deployed, err = runService.Projects.Locations.Services.ReplaceService(gcp.cloudRunServicePath(spec.Region, spec.Name), service).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to deploy Cloud Run service %s: %v", spec.Name, err)
+	}
+	if spec.NoWait {
+		slog.Info("GCP: Cloud Run service deployed", slog.String("Service", spec.Name), slog.String("Region", spec.Region), slog.String("Image", spec.Image))
+		return deployed, nil
+	}
+	slog.Info("GCP: Cloud Run service deployed, waiting for it to become ready", slog.String("Service", spec.Name),
+		slog.String("Region", spec.Region), slog.String("Image", spec.Image))
+	return gcp.waitForCloudRunService(ctx, spec.Region, spec.Name, deployed.Metadata.Generation)
+}
+
+// List the revisions of a service, newest first
+func (gcp *Gcp) ListCloudRunRevisions(region, serviceName string) ([]*cloudrun.Revision, error) {
+	runService, err := gcp.CloudRun()
+	if err != nil {
+		return nil, err
+	}
+	var revisions []*cloudrun.Revision
+	call := runService.Projects.Locations.Revisions.List(fmt.Sprintf("projects/%s/locations/%s", gcp.ProjectID, region)).
+		LabelSelector("serving.knative.dev/service=" + serviceName)
+	for {
+		response, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("gcp: unable to list revisions of %s: %v", serviceName, err)
+		}
+		revisions = append(revisions, response.Items...)
+		if response.Metadata == nil || response.Metadata.Continue == "" {
+			break
+		}
+		call.Continue(response.Metadata.Continue)
+	}
+	// RFC 3339 timestamps in UTC sort chronologically
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Metadata.CreationTimestamp > revisions[j].Metadata.CreationTimestamp
+	})
+	return revisions, nil
+}
+
+// SetCloudRunTraffic splits traffic between revisions by percent, the percents must add up to 100.
+// Use CloudRunLatestRevision to follow the latest ready revision. Existing tags are kept, tagged revisions
+// left out of traffic keep their tag without traffic. Waits until the routes are ready.
+func (gcp *Gcp) SetCloudRunTraffic(ctx context.Context, region, serviceName string, traffic map[string]int64) (*cloudrun.Service, error) {
+	var total int64
+	targets := make([]*cloudrun.TrafficTarget, 0, len(traffic))
+	for revision, percent := range traffic {
+		total += percent
+		if revision == CloudRunLatestRevision {
+			targets = append(targets, &cloudrun.TrafficTarget{LatestRevision: true, Percent: percent})
+		} else {
+			targets = append(targets, &cloudrun.TrafficTarget{RevisionName: revision, Percent: percent})
+		}
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("gcp: traffic for %s adds up to %d percent instead of 100", serviceName, total)
+	}
+
+	runService, err := gcp.CloudRun()
+	if err != nil {
+		return nil, err
+	}
+	name := gcp.cloudRunServicePath(region, serviceName)
+	service, err := runService.Projects.Locations.Services.Get(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to get Cloud Run service %s: %v", serviceName, err)
+	}
+	targets = keepTrafficTags(targets, service.Spec.Traffic)
+	sort.Slice(targets, func(i, j int) bool { return targets[i].RevisionName < targets[j].RevisionName })
+	service.Spec.Traffic = targets
+	service.Status = nil
+	updated, err := runService.Projects.Locations.Services.ReplaceService(name, service).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to update traffic of %s: %v", serviceName, err)
+	}
+	slog.Info("GCP: Cloud Run traffic updated", slog.String("Service", serviceName), slog.Any("Traffic", traffic))
+	return gcp.waitForCloudRunService(ctx, region, serviceName, updated.Metadata.Generation)
+}
+
+// RollbackCloudRunService sends all traffic to revision. An empty revision picks the newest ready revision
+// created before the one currently receiving the most traffic.
+func (gcp *Gcp) RollbackCloudRunService(ctx context.Context, region, serviceName, revision string) (*cloudrun.Service, error) {
+	if revision == "" {
+		runService, err := gcp.CloudRun()
+		if err != nil {
+			return nil, err
+		}
+		service, err := runService.Projects.Locations.Services.Get(gcp.cloudRunServicePath(region, serviceName)).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("gcp: unable to get Cloud Run service %s: %v", serviceName, err)
+		}
+		revisions, err := gcp.ListCloudRunRevisions(region, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		if revision = previousRevision(service, revisions); revision == "" {
+			return nil, fmt.Errorf("gcp: Cloud Run service %s has no earlier ready revision to roll back to", serviceName)
+		}
+	}
+	slog.Info("GCP: rolling back Cloud Run service", slog.String("Service", serviceName), slog.String("Revision", revision))
+	return gcp.SetCloudRunTraffic(ctx, region, serviceName, map[string]int64{revision: 100})
+}
+
+// waitForCloudRunService polls the service until it observed generation and is ready, or reports why it isn't
+func (gcp *Gcp) waitForCloudRunService(ctx context.Context, region, serviceName string, generation int64) (*cloudrun.Service, error) {
+	runService, err := gcp.CloudRun()
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	for {
+		service, err := runService.Projects.Locations.Services.Get(gcp.cloudRunServicePath(region, serviceName)).Context(ctx).Do()
+		if err != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("gcp: unable to get Cloud Run service %s: %v", serviceName, err)
+		}
+		if err == nil && service.Status != nil && service.Status.ObservedGeneration >= generation {
+			ready := readyCondition(service.Status.Conditions)
+			switch {
+			case ready != nil && ready.Status == "True":
+				slog.Info("GCP: Cloud Run service ready", slog.String("Service", serviceName), slog.String("Revision", service.Status.LatestReadyRevisionName),
+					slog.String("Url", service.Status.Url), slog.Duration("Elapsed", time.Since(started)))
+				return service, nil
+			case ready != nil && ready.Status == "False":
+				return service, fmt.Errorf("gcp: Cloud Run service %s failed to become ready: %s: %s", serviceName, ready.Reason, ready.Message)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gcp: Cloud Run service %s did not become ready in time: %v", serviceName, ctx.Err())
+		case <-time.After(cloudRunPollInterval):
+		}
+	}
+}
+
+// service builds the Knative service object for the spec
+func (spec CloudRunServiceSpec) service() *cloudrun.Service {
+	port := spec.Port
+	if port == 0 {
+		port = 8080
+	}
+	container := &cloudrun.Container{
+		Image: spec.Image,
+		Ports: []*cloudrun.ContainerPort{{ContainerPort: port}},
+	}
+	for _, name := range sortedKeys(spec.Env) {
+		container.Env = append(container.Env, &cloudrun.EnvVar{Name: name, Value: spec.Env[name]})
+	}
+	for _, name := range sortedKeys(spec.Secrets) {
+		secret := spec.Secrets[name]
+		if secret.Version == "" {
+			secret.Version = "latest"
+		}
+		container.Env = append(container.Env, &cloudrun.EnvVar{
+			Name:      name,
+			ValueFrom: &cloudrun.EnvVarSource{SecretKeyRef: &cloudrun.SecretKeySelector{Name: secret.Secret, Key: secret.Version}},
+		})
+	}
+	if spec.CPU != "" || spec.Memory != "" {
+		container.Resources = &cloudrun.ResourceRequirements{Limits: map[string]string{}}
+		if spec.CPU != "" {
+			container.Resources.Limits["cpu"] = spec.CPU
+		}
+		if spec.Memory != "" {
+			container.Resources.Limits["memory"] = spec.Memory
+		}
+	}
+
+	template := &cloudrun.RevisionTemplate{
+		Metadata: &cloudrun.ObjectMeta{Annotations: map[string]string{}},
+		Spec: &cloudrun.RevisionSpec{
+			Containers:           []*cloudrun.Container{container},
+			ContainerConcurrency: spec.Concurrency,
+			ServiceAccountName:   spec.ServiceAccount,
+		},
+	}
+	if spec.Revision != "" {
+		template.Metadata.Name = spec.Name + "-" + spec.Revision
+	}
+	if spec.MinInstances > 0 {
+		template.Metadata.Annotations["autoscaling.knative.dev/minScale"] = strconv.Itoa(spec.MinInstances)
+	}
+	if spec.MaxInstances > 0 {
+		template.Metadata.Annotations["autoscaling.knative.dev/maxScale"] = strconv.Itoa(spec.MaxInstances)
+	}
+
+	return &cloudrun.Service{
+		ApiVersion: "serving.knative.dev/v1",
+		Kind:       "Service",
+		Metadata:   &cloudrun.ObjectMeta{Name: spec.Name},
+		Spec: &cloudrun.ServiceSpec{
+			Template: template,
+			Traffic:  []*cloudrun.TrafficTarget{{LatestRevision: true, Percent: 100}},
+		},
+	}
+}
+
+// pinTraffic returns the current traffic split with the latest revision resolved to its name,
+// so a new revision doesn't receive any of it
+func pinTraffic(service *cloudrun.Service) []*cloudrun.TrafficTarget {
+	var targets []*cloudrun.TrafficTarget
+	if service.Status != nil {
+		for _, target := range service.Status.Traffic {
+			if target.Percent > 0 || target.Tag != "" {
+				targets = append(targets, &cloudrun.TrafficTarget{RevisionName: target.RevisionName, Percent: target.Percent, Tag: target.Tag})
+			}
+		}
+		if len(targets) == 0 && service.Status.LatestReadyRevisionName != "" {
+			targets = append(targets, &cloudrun.TrafficTarget{RevisionName: service.Status.LatestReadyRevisionName, Percent: 100})
+		}
+	}
+	return targets
+}
+
+// keepTrafficTags moves the tags of the current traffic targets onto the new ones, adding a target without
+// traffic for tagged revisions that no longer receive any
+func keepTrafficTags(targets, current []*cloudrun.TrafficTarget) []*cloudrun.TrafficTarget {
+	for _, tagged := range current {
+		if tagged.Tag == "" {
+			continue
+		}
+		index := slices.IndexFunc(targets, func(target *cloudrun.TrafficTarget) bool {
+			return target.Tag == "" && target.LatestRevision == tagged.LatestRevision && target.RevisionName == tagged.RevisionName
+		})
+		if index >= 0 {
+			targets[index].Tag = tagged.Tag
+			continue
+		}
+		targets = append(targets, &cloudrun.TrafficTarget{RevisionName: tagged.RevisionName, LatestRevision: tagged.LatestRevision, Tag: tagged.Tag})
+	}
+	return targets
+}
+
+// previousRevision returns the newest ready revision created before the one serving the most traffic
+func previousRevision(service *cloudrun.Service, revisions []*cloudrun.Revision) string {
+	var serving string
+	var servingPercent int64 = -1
+	if service.Status != nil {
+		for _, target := range service.Status.Traffic {
+			if target.Percent > servingPercent {
+				serving, servingPercent = target.RevisionName, target.Percent
+			}
+		}
+	}
+	index := slices.IndexFunc(revisions, func(r *cloudrun.Revision) bool { return r.Metadata.Name == serving })
+	if index < 0 {
+		return ""
+	}
+	for _, revision := range revisions[index+1:] {
+		if revision.Status != nil {
+			if ready := readyCondition(revision.Status.Conditions); ready != nil && ready.Status == "True" {
+				return revision.Metadata.Name
+			}
+		}
+	}
+	return ""
+}
+
+func readyCondition(conditions []*cloudrun.GoogleCloudRunV1Condition) *cloudrun.GoogleCloudRunV1Condition {
+	for _, condition := range conditions {
+		if condition.Type == "Ready" {
+			return condition
+		}
+	}
+	return nil
+}
+
+func (gcp *Gcp) cloudRunServicePath(region, serviceName string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/services/%s", gcp.ProjectID, region, serviceName)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
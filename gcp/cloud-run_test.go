@@ -0,0 +1,258 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"google.golang.org/api/option"
+	cloudrun "google.golang.org/api/run/v1"
+)
+
+// newTestCloudRun returns a Gcp whose Cloud Run client talks to handler
+func newTestCloudRun(t *testing.T, handler http.HandlerFunc) *Gcp {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	service, err := cloudrun.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("cloudrun.NewService() error = %v", err)
+	}
+	return &Gcp{ProjectID: "test", CloudRunService: service}
+}
+
+func TestDeployCloudRunAppExistingService(t *testing.T) {
+	var writes int
+	gcp := newTestCloudRun(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes++
+		}
+		w.Write([]byte(`{"metadata":{"name":"web","resourceVersion":"1"}}`))
+	})
+
+	if _, _, err := gcp.DeployCloudRunApp("web", "web:v2", "europe-west1"); err == nil {
+		t.Error("DeployCloudRunApp() expected error for an existing service")
+	}
+	if writes != 0 {
+		t.Errorf("DeployCloudRunApp() sent %d writes, want none", writes)
+	}
+}
+
+func TestCloudRunServiceSpec(t *testing.T) {
+	spec := CloudRunServiceSpec{
+		Name:           "web",
+		Image:          "web:v2",
+		Revision:       "v2",
+		Env:            map[string]string{"B": "2", "A": "1"},
+		Secrets:        map[string]CloudRunSecret{"DB_PASSWORD": {Secret: "db"}, "API_KEY": {Secret: "api", Version: "3"}},
+		CPU:            "1",
+		MinInstances:   1,
+		MaxInstances:   5,
+		Concurrency:    10,
+		ServiceAccount: "run@test.iam.gserviceaccount.com",
+	}
+	service := spec.service()
+	template := service.Spec.Template
+	container := template.Spec.Containers[0]
+
+	if template.Metadata.Name != "web-v2" {
+		t.Errorf("revision name = %s, want web-v2", template.Metadata.Name)
+	}
+	if port := container.Ports[0].ContainerPort; port != 8080 {
+		t.Errorf("port = %d, want the 8080 default", port)
+	}
+	var env []string
+	for _, e := range container.Env {
+		if e.ValueFrom != nil {
+			ref := e.ValueFrom.SecretKeyRef
+			env = append(env, e.Name+"="+ref.Name+":"+ref.Key)
+		} else {
+			env = append(env, e.Name+"="+e.Value)
+		}
+	}
+	wantEnv := []string{"A=1", "B=2", "API_KEY=api:3", "DB_PASSWORD=db:latest"}
+	if !slices.Equal(env, wantEnv) {
+		t.Errorf("env = %v, want %v", env, wantEnv)
+	}
+	if limits := container.Resources.Limits; limits["cpu"] != "1" || len(limits) != 1 {
+		t.Errorf("limits = %v, want only cpu", limits)
+	}
+	annotations := template.Metadata.Annotations
+	if annotations["autoscaling.knative.dev/minScale"] != "1" || annotations["autoscaling.knative.dev/maxScale"] != "5" {
+		t.Errorf("scaling annotations = %v", annotations)
+	}
+	if template.Spec.ContainerConcurrency != 10 || template.Spec.ServiceAccountName != spec.ServiceAccount {
+		t.Errorf("revision spec = %+v", template.Spec)
+	}
+	if traffic := service.Spec.Traffic; len(traffic) != 1 || !traffic[0].LatestRevision || traffic[0].Percent != 100 {
+		t.Errorf("traffic = %+v, want all on the latest revision", traffic)
+	}
+
+	minimal := CloudRunServiceSpec{Name: "web", Image: "web:v1", Port: 9000}.service()
+	if minimal.Spec.Template.Metadata.Name != "" || minimal.Spec.Template.Spec.Containers[0].Resources != nil {
+		t.Errorf("minimal spec set a revision name or resources: %+v", minimal.Spec.Template)
+	}
+	if port := minimal.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort; port != 9000 {
+		t.Errorf("port = %d, want 9000", port)
+	}
+}
+
+func TestPinTraffic(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *cloudrun.ServiceStatus
+		want   []string
+	}{
+		{"split", &cloudrun.ServiceStatus{Traffic: []*cloudrun.TrafficTarget{
+			{RevisionName: "web-2", Percent: 90, LatestRevision: true},
+			{RevisionName: "web-1", Percent: 10},
+			{RevisionName: "web-0", Percent: 0},
+		}}, []string{"web-2:90", "web-1:10"}},
+		{"tagged revision without traffic", &cloudrun.ServiceStatus{Traffic: []*cloudrun.TrafficTarget{
+			{RevisionName: "web-2", Percent: 100},
+			{RevisionName: "web-3", Tag: "canary"},
+		}}, []string{"web-2:100", "web-3:0"}},
+		{"no traffic reported", &cloudrun.ServiceStatus{LatestReadyRevisionName: "web-2"}, []string{"web-2:100"}},
+		{"no status", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, target := range pinTraffic(&cloudrun.Service{Status: tt.status}) {
+				if target.LatestRevision {
+					t.Errorf("target %s still follows the latest revision", target.RevisionName)
+				}
+				got = append(got, target.RevisionName+":"+strconv.FormatInt(target.Percent, 10))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pinTraffic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousRevision(t *testing.T) {
+	revision := func(name string, ready bool) *cloudrun.Revision {
+		status := "False"
+		if ready {
+			status = "True"
+		}
+		return &cloudrun.Revision{
+			Metadata: &cloudrun.ObjectMeta{Name: name},
+			Status:   &cloudrun.RevisionStatus{Conditions: []*cloudrun.GoogleCloudRunV1Condition{{Type: "Ready", Status: status}}},
+		}
+	}
+	// Newest first, as ListCloudRunRevisions returns them
+	revisions := []*cloudrun.Revision{revision("web-4", true), revision("web-3", true), revision("web-2", false), revision("web-1", true)}
+	serving := func(traffic ...*cloudrun.TrafficTarget) *cloudrun.Service {
+		return &cloudrun.Service{Status: &cloudrun.ServiceStatus{Traffic: traffic}}
+	}
+
+	tests := []struct {
+		name    string
+		service *cloudrun.Service
+		want    string
+	}{
+		{"latest serving", serving(&cloudrun.TrafficTarget{RevisionName: "web-4", Percent: 100}), "web-3"},
+		{"skips unready revisions", serving(&cloudrun.TrafficTarget{RevisionName: "web-3", Percent: 100}), "web-1"},
+		{"most traffic wins", serving(&cloudrun.TrafficTarget{RevisionName: "web-4", Percent: 20}, &cloudrun.TrafficTarget{RevisionName: "web-3", Percent: 80}), "web-1"},
+		{"oldest serving", serving(&cloudrun.TrafficTarget{RevisionName: "web-1", Percent: 100}), ""},
+		{"unknown revision", serving(&cloudrun.TrafficTarget{RevisionName: "web-9", Percent: 100}), ""},
+		{"no status", &cloudrun.Service{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previousRevision(tt.service, revisions); got != tt.want {
+				t.Errorf("previousRevision() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployCloudRunAppNoWait(t *testing.T) {
+	var requests []string
+	gcp := newTestCloudRun(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method)
+		if r.Method == http.MethodGet {
+			http.Error(w, `{"error":{"code":404}}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"metadata":{"name":"web","uid":"web-uid"}}`))
+	})
+
+	uid, url, err := gcp.DeployCloudRunApp("web", "web:v1", "europe-west1")
+	if err != nil {
+		t.Fatalf("DeployCloudRunApp() error = %v", err)
+	}
+	if uid != "web-uid" || url != "" {
+		t.Errorf("DeployCloudRunApp() = %s, %s, want web-uid and no url", uid, url)
+	}
+	if want := []string{http.MethodGet, http.MethodPost}; !slices.Equal(requests, want) {
+		t.Errorf("requests = %v, want %v without waiting for readiness", requests, want)
+	}
+}
+
+func TestDeployCloudRunServiceKeepsMetadata(t *testing.T) {
+	var replaced cloudrun.Service
+	gcp := newTestCloudRun(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &replaced)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"metadata":{"name":"web","resourceVersion":"7",` +
+			`"labels":{"team":"web"},"annotations":{"run.googleapis.com/ingress":"internal"}},` +
+			`"status":{"conditions":[{"type":"Ready","status":"True"}]}}`))
+	})
+
+	_, err := gcp.DeployCloudRunService(context.Background(), CloudRunServiceSpec{Name: "web", Image: "web:v2", Region: "europe-west1"})
+	if err != nil {
+		t.Fatalf("DeployCloudRunService() error = %v", err)
+	}
+	metadata := replaced.Metadata
+	if metadata.ResourceVersion != "7" || metadata.Labels["team"] != "web" || metadata.Annotations["run.googleapis.com/ingress"] != "internal" {
+		t.Errorf("replaced metadata = %+v, want the existing labels and annotations", metadata)
+	}
+	if image := replaced.Spec.Template.Spec.Containers[0].Image; image != "web:v2" {
+		t.Errorf("image = %s, want web:v2", image)
+	}
+}
+
+func TestSetCloudRunTrafficKeepsTags(t *testing.T) {
+	var replaced cloudrun.Service
+	gcp := newTestCloudRun(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &replaced)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"metadata":{"name":"web"},"spec":{"traffic":[` +
+			`{"revisionName":"web-2","percent":100,"tag":"stable"},` +
+			`{"revisionName":"web-3","tag":"canary"},` +
+			`{"latestRevision":true,"tag":"head"}]},` +
+			`"status":{"conditions":[{"type":"Ready","status":"True"}]}}`))
+	})
+
+	_, err := gcp.SetCloudRunTraffic(context.Background(), "europe-west1", "web", map[string]int64{"web-2": 90, "web-1": 10})
+	if err != nil {
+		t.Fatalf("SetCloudRunTraffic() error = %v", err)
+	}
+	var got []string
+	for _, target := range replaced.Spec.Traffic {
+		revision := target.RevisionName
+		if target.LatestRevision {
+			revision = CloudRunLatestRevision
+		}
+		got = append(got, revision+":"+strconv.FormatInt(target.Percent, 10)+":"+target.Tag)
+	}
+	want := []string{"LATEST:0:head", "web-1:10:", "web-2:90:stable", "web-3:0:canary"}
+	if !slices.Equal(got, want) {
+		t.Errorf("traffic = %v, want %v", got, want)
+	}
+}
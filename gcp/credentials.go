@@ -0,0 +1,113 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// Service names one of the API clients held by Gcp
+type Service string
+
+const (
+	ServiceIAM             Service = "iam"
+	ServiceSecretManager   Service = "secretmanager"
+	ServiceSQL             Service = "sqladmin"
+	ServiceStorageTransfer Service = "storagetransfer"
+	ServiceCloudRun        Service = "run"
+	ServiceStorage         Service = "storage"
+	ServiceResourceManager Service = "cloudresourcemanager"
+)
+
+// DefaultCredentialsFile is used when no credential source is configured and the file exists
+const DefaultCredentialsFile = "config/sa.json"
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// CredentialOptions selects how Gcp authenticates. The first configured source wins: JSON, File,
+// DefaultCredentialsFile, then Application Default Credentials, which also cover gcloud user credentials
+// and workload identity on GKE or through the metadata server.
+type CredentialOptions struct {
+	JSON   []byte // Service account key or workload identity federation config held in memory, e.g. read from a secret
+	File   string // Path to a service account key or workload identity federation config
+	UseADC bool   // Use Application Default Credentials even if DefaultCredentialsFile exists
+
+	ImpersonateServiceAccount string   // Act as this service account using the resolved credentials
+	Delegates                 []string // Delegation chain from the credentials to the impersonated account
+	Scopes                    []string // Defaults to cloud-platform
+
+	Endpoints map[Service]string // Per-client endpoint overrides, e.g. for emulators or Private Service Connect
+}
+
+// clientOptions resolves the credentials into client options shared by every client, along with the
+// project they belong to when it is known
+func (o CredentialOptions) clientOptions(ctx context.Context) ([]option.ClientOption, string, error) {
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{cloudPlatformScope}
+	}
+
+	var creds *google.Credentials
+	var err error
+	switch {
+	case len(o.JSON) > 0:
+		creds, err = google.CredentialsFromJSON(ctx, o.JSON, scopes...)
+	case o.File != "":
+		creds, err = credentialsFromFile(ctx, o.File, scopes)
+	case !o.UseADC && fileExists(DefaultCredentialsFile):
+		creds, err = credentialsFromFile(ctx, DefaultCredentialsFile, scopes)
+	default:
+		creds, err = google.FindDefaultCredentials(ctx, scopes...)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("gcp: unable to load credentials: %v", err)
+	}
+
+	if o.ImpersonateServiceAccount == "" {
+		return []option.ClientOption{option.WithCredentials(creds)}, creds.ProjectID, nil
+	}
+	tokenSource, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: o.ImpersonateServiceAccount,
+		Scopes:          scopes,
+		Delegates:       o.Delegates,
+	}, option.WithCredentials(creds))
+	if err != nil {
+		return nil, "", fmt.Errorf("gcp: unable to impersonate %s: %v", o.ImpersonateServiceAccount, err)
+	}
+	projectID := projectFromServiceAccount(o.ImpersonateServiceAccount)
+	if projectID == "" {
+		projectID = creds.ProjectID
+	}
+	return []option.ClientOption{option.WithTokenSource(tokenSource)}, projectID, nil
+}
+
+func credentialsFromFile(ctx context.Context, path string, scopes []string) (*google.Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return google.CredentialsFromJSON(ctx, data, scopes...)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// projectFromServiceAccount extracts the project from a name@project.iam.gserviceaccount.com address
+func projectFromServiceAccount(email string) string {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return ""
+	}
+	project, ok := strings.CutSuffix(domain, ".iam.gserviceaccount.com")
+	if !ok {
+		return ""
+	}
+	return project
+}
@@ -0,0 +1,22 @@
+package gcp
+
+import "testing"
+
+func TestProjectFromServiceAccount(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"deployer@my-project.iam.gserviceaccount.com", "my-project"},
+		{"123456-compute@developer.gserviceaccount.com", ""},
+		{"my-project@appspot.gserviceaccount.com", ""},
+		{"jane@example.com", ""},
+		{"not-an-email", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := projectFromServiceAccount(tt.email); got != tt.want {
+			t.Errorf("projectFromServiceAccount(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"sync"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	storage "cloud.google.com/go/storage"
 	storagetransfer "cloud.google.com/go/storagetransfer/apiv1"
 	"cloud.google.com/go/storagetransfer/apiv1/storagetransferpb"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
 	"google.golang.org/api/iam/v1"
 	"google.golang.org/api/option"
 	cloudrun "google.golang.org/api/run/v1"
@@ -19,46 +21,50 @@ import (
 )
 
 type Gcp struct {
-	IamService            *iam.Service            `json:"-"` // Exclude from JSON
-	SecretManagerService  *secretmanager.Client   `json:"-"` // Exclude from JSON
-	SqlService            *sqladmin.Service       `json:"-"` // Exclude from JSON
-	storageTransferClient *storagetransfer.Client `json:"-"` // Exclude from JSON
-	CloudRunService       *cloudrun.APIService    `json:"-"` // Exclude from JSON
-	StorageService        *storage.Client         `json:"-"` // Exclude from JSON
-	Env                   string                  `json:"env"`
-	ProjectID             string                  `json:"projectId"`
+	Credentials CredentialOptions `json:"-"` // Exclude from JSON
+	Services    []Service         `json:"-"` // Services that may be used, empty enables all of them
+	Env         string            `json:"env"`
+	ProjectID   string            `json:"projectId"` // Taken from the credentials when empty
+	KeyPolicy   *KeyPolicy        `json:"-"`         // Policy used by AuditServiceAccountKeys, nil uses DefaultKeyPolicy
+
+	// Setting a client field makes the accessor in clients.go return it instead of creating a client. The accessors
+	// never write these fields and Close leaves such clients open, they stay owned by the caller.
+
+	// Deprecated: use IAM, which creates the client on first use
+	IamService *iam.Service `json:"-"`
+	// Deprecated: use SecretManager, which creates the client on first use
+	SecretManagerService *secretmanager.Client `json:"-"`
+	// Deprecated: use SQL, which creates the client on first use
+	SqlService *sqladmin.Service `json:"-"`
+	// Deprecated: use CloudRun, which creates the client on first use
+	CloudRunService *cloudrun.APIService `json:"-"`
+	// Deprecated: use Storage, which creates the client on first use
+	StorageService *storage.Client `json:"-"`
+
+	mu                    sync.Mutex
+	options               []option.ClientOption
+	iamClient             *iam.Service
+	secretManagerClient   *secretmanager.Client
+	sqlClient             *sqladmin.Service
+	storageTransferClient *storagetransfer.Client
+	cloudRunClient        *cloudrun.APIService
+	storageClient         *storage.Client
+	resourceManager       *resourcemanager.Service
 }
 
-// Initc initializes the c client
+// InitGcp resolves the credentials, the clients themselves are created when first used
 func (c *Gcp) InitGcp() error { //V
 	slog.Info("GCP: init client", slog.String("Env", c.Env))
-	credentials := "config/sa.json"
-	gcpContext := context.Background()
-
-	var err error
-	c.IamService, err = iam.NewService(gcpContext, option.WithCredentialsFile(credentials))
-	if err != nil {
-		return fmt.Errorf("gcp: unable to create iam service client: %v", err)
-	}
-	c.SecretManagerService, err = secretmanager.NewClient(gcpContext, option.WithCredentialsFile(credentials))
-	if err != nil {
-		return fmt.Errorf("gcp: unable to create secretManager service client: %v", err)
-	}
-	c.SqlService, err = sqladmin.NewService(gcpContext, option.WithCredentialsFile(credentials))
+	options, projectID, err := c.Credentials.clientOptions(context.Background())
 	if err != nil {
-		return fmt.Errorf("gcp: unable to create sql service client: %v", err)
+		return err
 	}
-	c.storageTransferClient, err = storagetransfer.NewClient(context.Background(), option.WithCredentialsFile(credentials))
-	if err != nil {
-		log.Fatalf("Failed to create client: %v", err)
-	}
-	c.CloudRunService, err = cloudrun.NewService(gcpContext, option.WithCredentialsFile(credentials))
-	if err != nil {
-		return fmt.Errorf("gcp: unable to create cloudrun service client: %v", err)
-	}
-	c.StorageService, err = storage.NewClient(gcpContext, option.WithCredentialsFile(credentials))
-	if err != nil {
-		return fmt.Errorf("gcp: unable to create storage service client: %v", err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.options = options
+	if c.ProjectID == "" {
+		c.ProjectID = projectID
 	}
 	slog.Info("GCP: client initialized", slog.String("Project", c.ProjectID), slog.String("Env", c.Env))
 	return nil
@@ -72,7 +78,11 @@ func (c *Gcp) TriggerTransferJob(projectID, transferJobName string) error {
 		ProjectId: projectID,
 	}
 
-	_, err := c.storageTransferClient.RunTransferJob(ctx, req)
+	client, err := c.StorageTransfer()
+	if err != nil {
+		return err
+	}
+	_, err = client.RunTransferJob(ctx, req)
 	if err != nil {
 		return fmt.Errorf("client.RunTransferJob: %v", err)
 	}
@@ -81,47 +91,26 @@ func (c *Gcp) TriggerTransferJob(projectID, transferJobName string) error {
 	return nil
 }
 
+// DeployCloudRunApp creates a service with the default settings and returns its uid and url without waiting for it
+// to become ready, it fails if the service already exists. Use DeployCloudRunService to configure or replace services.
 func (c *Gcp) DeployCloudRunApp(serviceName, imageURL, region string) (string, string, error) {
-	// Define the parent location in Cloud Run
-	parent := fmt.Sprintf("projects/%s/locations/%s", c.ProjectID, region)
-
-	// Define the Cloud Run service specification
-	service := &cloudrun.Service{
-		ApiVersion: "serving.knative.dev/v1",
-		Kind:       "Service",
-		Metadata: &cloudrun.ObjectMeta{
-			Name: serviceName,
-		},
-		Spec: &cloudrun.ServiceSpec{
-			Template: &cloudrun.RevisionTemplate{
-				Spec: &cloudrun.RevisionSpec{
-					Containers: []*cloudrun.Container{
-						{
-							Image: imageURL,
-							Ports: []*cloudrun.ContainerPort{
-								{
-									ContainerPort: 8080, // Cloud Run requires port 8080
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	// Deploy the service to Cloud Run
-	app, err := c.CloudRunService.Projects.Locations.Services.Create(parent, service).Do()
+	app, err := c.DeployCloudRunService(context.Background(), CloudRunServiceSpec{Name: serviceName, Image: imageURL, Region: region, CreateOnly: true, NoWait: true})
 	if err != nil {
-		return "", "", fmt.Errorf("failed to deploy service to Cloud Run: %v", err)
+		return "", "", err
 	}
-
-	fmt.Printf("Service %s deployed successfully to region %s!\n", serviceName, region)
-	return app.Metadata.Uid, app.Status.Url, nil
+	var url string
+	if app.Status != nil {
+		url = app.Status.Url
+	}
+	return app.Metadata.Uid, url, nil
 }
 
 // UploadFileToGCS uploads a local file to a specified c bucket
 func (c *Gcp) UploadFileToGCS(bucketName, objectName, filePath string) error {
+	client, err := c.Storage()
+	if err != nil {
+		return err
+	}
 
 	// Open the local file
 	file, err := os.Open(filePath)
@@ -131,7 +120,7 @@ func (c *Gcp) UploadFileToGCS(bucketName, objectName, filePath string) error {
 	defer file.Close()
 
 	// Create a writer for the bucket
-	wc := c.StorageService.Bucket(bucketName).Object(objectName).NewWriter(context.Background())
+	wc := client.Bucket(bucketName).Object(objectName).NewWriter(context.Background())
 	defer func() {
 		if closeErr := wc.Close(); closeErr != nil {
 			err = fmt.Errorf("failed to close writer for bucket %s: %v", bucketName, closeErr)
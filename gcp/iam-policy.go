@@ -0,0 +1,162 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+)
+
+// Attempts made by the IAM policy updates when the policy keeps changing between read and write
+const policyUpdateAttempts = 5
+
+// Policy version that keeps conditional bindings intact on read-modify-write
+const policyVersion = 3
+
+// ServiceAccountMember returns the IAM member for a service account, e.g. for AddProjectIAMMember
+func ServiceAccountMember(saEmail string) string {
+	return "serviceAccount:" + saEmail
+}
+
+// AddProjectIAMMember grants role on the project to member, e.g. user:jane@example.com or serviceAccount:...
+func (gcp *Gcp) AddProjectIAMMember(ctx context.Context, role, member string) error {
+	return gcp.updateProjectPolicy(ctx, role, member, true)
+}
+
+// RemoveProjectIAMMember revokes role on the project from member
+func (gcp *Gcp) RemoveProjectIAMMember(ctx context.Context, role, member string) error {
+	return gcp.updateProjectPolicy(ctx, role, member, false)
+}
+
+// AddServiceAccountIAMMember grants role on the service account to member, e.g. roles/iam.workloadIdentityUser
+func (gcp *Gcp) AddServiceAccountIAMMember(ctx context.Context, saEmail, role, member string) error {
+	return gcp.updateServiceAccountPolicy(ctx, saEmail, role, member, true)
+}
+
+// RemoveServiceAccountIAMMember revokes role on the service account from member
+func (gcp *Gcp) RemoveServiceAccountIAMMember(ctx context.Context, saEmail, role, member string) error {
+	return gcp.updateServiceAccountPolicy(ctx, saEmail, role, member, false)
+}
+
+func (gcp *Gcp) updateProjectPolicy(ctx context.Context, role, member string, add bool) error {
+	client, err := gcp.ResourceManager()
+	if err != nil {
+		return err
+	}
+	resource := "projects/" + gcp.ProjectID
+	return retryPolicyUpdate(ctx, resource, role, member, add, func() (bool, error) {
+		policy, err := client.Projects.GetIamPolicy(resource, &resourcemanager.GetIamPolicyRequest{
+			Options: &resourcemanager.GetPolicyOptions{RequestedPolicyVersion: policyVersion},
+		}).Context(ctx).Do()
+		if err != nil {
+			return false, err
+		}
+		var binding *resourcemanager.Binding
+		for _, b := range policy.Bindings {
+			if b.Role == role && b.Condition == nil {
+				binding = b
+				break
+			}
+		}
+		if binding == nil {
+			if !add {
+				return false, nil
+			}
+			binding = &resourcemanager.Binding{Role: role}
+			policy.Bindings = append(policy.Bindings, binding)
+		}
+		var changed bool
+		if binding.Members, changed = setMember(binding.Members, member, add); !changed {
+			return false, nil
+		}
+		policy.Bindings = slices.DeleteFunc(policy.Bindings, func(b *resourcemanager.Binding) bool { return len(b.Members) == 0 })
+		policy.Version = policyVersion
+		// The etag read above makes the write fail with a conflict if the policy changed in between
+		_, err = client.Projects.SetIamPolicy(resource, &resourcemanager.SetIamPolicyRequest{Policy: policy}).Context(ctx).Do()
+		return true, err
+	})
+}
+
+func (gcp *Gcp) updateServiceAccountPolicy(ctx context.Context, saEmail, role, member string, add bool) error {
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return err
+	}
+	resource := serviceAccountPath(saEmail)
+	return retryPolicyUpdate(ctx, resource, role, member, add, func() (bool, error) {
+		policy, err := iamService.Projects.ServiceAccounts.GetIamPolicy(resource).OptionsRequestedPolicyVersion(policyVersion).Context(ctx).Do()
+		if err != nil {
+			return false, err
+		}
+		var binding *iam.Binding
+		for _, b := range policy.Bindings {
+			if b.Role == role && b.Condition == nil {
+				binding = b
+				break
+			}
+		}
+		if binding == nil {
+			if !add {
+				return false, nil
+			}
+			binding = &iam.Binding{Role: role}
+			policy.Bindings = append(policy.Bindings, binding)
+		}
+		var changed bool
+		if binding.Members, changed = setMember(binding.Members, member, add); !changed {
+			return false, nil
+		}
+		policy.Bindings = slices.DeleteFunc(policy.Bindings, func(b *iam.Binding) bool { return len(b.Members) == 0 })
+		policy.Version = policyVersion
+		_, err = iamService.Projects.ServiceAccounts.SetIamPolicy(resource, &iam.SetIamPolicyRequest{Policy: policy}).Context(ctx).Do()
+		return true, err
+	})
+}
+
+// retryPolicyUpdate runs a read-modify-write of an IAM policy again while it fails on an etag conflict.
+// update reports whether it wrote the policy, it doesn't when the member already had the wanted state.
+func retryPolicyUpdate(ctx context.Context, resource, role, member string, add bool, update func() (bool, error)) error {
+	action := "remove"
+	if add {
+		action = "add"
+	}
+	for attempt := 1; ; attempt++ {
+		written, err := update()
+		if err == nil {
+			if written {
+				slog.Info("GCP: IAM policy updated", slog.String("Resource", resource), slog.String("Role", role),
+					slog.String("Member", member), slog.String("Action", action))
+			}
+			return nil
+		}
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusConflict || attempt == policyUpdateAttempts {
+			return fmt.Errorf("gcp: unable to %s %s for %s on %s: %v", action, role, member, resource, err)
+		}
+		slog.Warn("GCP: IAM policy changed during update, retrying", slog.String("Resource", resource), slog.Int("Attempt", attempt))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gcp: unable to %s %s for %s on %s: %v", action, role, member, resource, ctx.Err())
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		}
+	}
+}
+
+// setMember adds or removes member and reports whether the list changed
+func setMember(members []string, member string, add bool) ([]string, bool) {
+	i := slices.Index(members, member)
+	switch {
+	case add && i < 0:
+		return append(members, member), true
+	case !add && i >= 0:
+		return slices.Delete(members, i, i+1), true
+	}
+	return members, false
+}
@@ -0,0 +1,32 @@
+package gcp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSetMember(t *testing.T) {
+	tests := []struct {
+		name        string
+		members     []string
+		member      string
+		add         bool
+		want        []string
+		wantChanged bool
+	}{
+		{"add new", []string{"user:a"}, "user:b", true, []string{"user:a", "user:b"}, true},
+		{"add to empty", nil, "user:a", true, []string{"user:a"}, true},
+		{"add existing", []string{"user:a"}, "user:a", true, []string{"user:a"}, false},
+		{"remove existing", []string{"user:a", "user:b", "user:c"}, "user:b", false, []string{"user:a", "user:c"}, true},
+		{"remove last", []string{"user:a"}, "user:a", false, []string{}, true},
+		{"remove missing", []string{"user:a"}, "user:b", false, []string{"user:a"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := setMember(slices.Clone(tt.members), tt.member, tt.add)
+			if !slices.Equal(got, tt.want) || changed != tt.wantChanged {
+				t.Errorf("setMember() = %v, %v, want %v, %v", got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"time"
+
+	"google.golang.org/api/iam/v1"
+)
+
+// KeyStatus is the outcome of checking a key against a KeyPolicy
+type KeyStatus string
+
+const (
+	KeyCompliant KeyStatus = "compliant"
+	KeyWarning   KeyStatus = "warning"
+	KeyViolating KeyStatus = "violating"
+	KeySkipped   KeyStatus = "skipped" // Not covered by the policy
+)
+
+// KeyPolicy decides when a service account key is too old
+type KeyPolicy struct {
+	MaxAge          time.Duration // Keys older than this violate the policy, 0 disables the check
+	WarnAge         time.Duration // Keys older than this get a warning, 0 disables the warning
+	KeyTypes        []string      // Key types to check, e.g. USER_MANAGED, empty checks all of them
+	IncludeDisabled bool          // Also check disabled keys, they are skipped by default since they can't authenticate
+}
+
+// DefaultKeyPolicy allows user-managed keys for 90 days and warns two weeks before
+var DefaultKeyPolicy = KeyPolicy{
+	MaxAge:   90 * 24 * time.Hour,
+	WarnAge:  76 * 24 * time.Hour,
+	KeyTypes: []string{"USER_MANAGED"},
+}
+
+// KeyFinding is the result of checking one key
+type KeyFinding struct {
+	ServiceAccount string
+	Key            string // Key ID
+	KeyType        string
+	Disabled       bool
+	Age            time.Duration
+	Status         KeyStatus
+	Reason         string
+}
+
+// KeyAuditReport lists the keys of every service account in the project by status
+type KeyAuditReport struct {
+	Policy          KeyPolicy
+	ServiceAccounts int
+	Compliant       []KeyFinding
+	Warning         []KeyFinding
+	Violating       []KeyFinding
+	Skipped         int
+	Errors          map[string]error // Service accounts whose keys couldn't be listed
+	StartedAt       time.Time
+	FinishedAt      time.Time
+}
+
+// Evaluate checks a key against the policy. Keys with an unreadable creation time violate it,
+// keys past their expiry time only get a warning since they can no longer authenticate.
+func (p KeyPolicy) Evaluate(key *iam.ServiceAccountKey) KeyFinding {
+	return p.evaluate(key, time.Now())
+}
+
+func (p KeyPolicy) evaluate(key *iam.ServiceAccountKey, now time.Time) KeyFinding {
+	finding := KeyFinding{Key: path.Base(key.Name), KeyType: key.KeyType, Disabled: key.Disabled, Status: KeyCompliant}
+	if len(p.KeyTypes) > 0 && !slices.Contains(p.KeyTypes, key.KeyType) {
+		finding.Status, finding.Reason = KeySkipped, "key type "+key.KeyType+" is not covered"
+		return finding
+	}
+	if key.Disabled && !p.IncludeDisabled {
+		finding.Status, finding.Reason = KeySkipped, "key is disabled"
+		return finding
+	}
+
+	// A key of unknown age is flagged for a look but never counts as violating, rotation would delete it
+	created, err := time.Parse(time.RFC3339, key.ValidAfterTime)
+	if err != nil {
+		finding.Status, finding.Reason = KeyWarning, fmt.Sprintf("unable to parse creation time %q", key.ValidAfterTime)
+		return finding
+	}
+	finding.Age = now.Sub(created)
+	switch {
+	case p.MaxAge > 0 && finding.Age > p.MaxAge:
+		finding.Status, finding.Reason = KeyViolating, fmt.Sprintf("older than %d days", int(p.MaxAge.Hours()/24))
+	case keyExpired(key, now):
+		finding.Status, finding.Reason = KeyWarning, "expired on "+key.ValidBeforeTime
+	case p.WarnAge > 0 && finding.Age > p.WarnAge:
+		finding.Status, finding.Reason = KeyWarning, fmt.Sprintf("older than %d days", int(p.WarnAge.Hours()/24))
+	}
+	return finding
+}
+
+// AuditServiceAccountKeys checks the keys of every service account in the project against gcp.KeyPolicy, or
+// DefaultKeyPolicy when it isn't set. Accounts whose keys can't be listed are recorded in the report and skipped.
+func (gcp *Gcp) AuditServiceAccountKeys(ctx context.Context) (*KeyAuditReport, error) {
+	policy := DefaultKeyPolicy
+	if gcp.KeyPolicy != nil {
+		policy = *gcp.KeyPolicy
+	}
+	report := &KeyAuditReport{Policy: policy, Errors: map[string]error{}, StartedAt: time.Now()}
+	defer func() { report.FinishedAt = time.Now() }()
+
+	accounts, err := gcp.listServiceAccounts(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.ServiceAccounts = len(accounts)
+	for _, account := range accounts {
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("gcp: key audit stopped: %v", err)
+		}
+		keys, err := gcp.GetServiceAccountKeys(account.Email)
+		if err != nil {
+			report.Errors[account.Email] = err
+			continue
+		}
+		now := time.Now()
+		for _, key := range keys {
+			finding := policy.evaluate(key, now)
+			finding.ServiceAccount = account.Email
+			switch finding.Status {
+			case KeyCompliant:
+				report.Compliant = append(report.Compliant, finding)
+			case KeyWarning:
+				report.Warning = append(report.Warning, finding)
+			case KeyViolating:
+				report.Violating = append(report.Violating, finding)
+			default:
+				report.Skipped++
+			}
+		}
+	}
+	slog.Info("GCP: key audit finished", slog.Int("ServiceAccounts", report.ServiceAccounts), slog.Int("Compliant", len(report.Compliant)),
+		slog.Int("Warning", len(report.Warning)), slog.Int("Violating", len(report.Violating)), slog.Int("Errors", len(report.Errors)))
+	return report, nil
+}
+
+// keyExpired reports whether the key is past its expiry time, keys that never expire report the year 9999
+func keyExpired(key *iam.ServiceAccountKey, now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, key.ValidBeforeTime)
+	return err == nil && expires.Before(now)
+}
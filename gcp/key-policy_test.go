@@ -0,0 +1,43 @@
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/api/iam/v1"
+)
+
+func TestKeyPolicyEvaluate(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	created := func(age time.Duration) string { return now.Add(-age).Format(time.RFC3339) }
+	day := 24 * time.Hour
+	policy := KeyPolicy{MaxAge: 90 * day, WarnAge: 76 * day, KeyTypes: []string{"USER_MANAGED"}}
+
+	tests := []struct {
+		name   string
+		policy KeyPolicy
+		key    iam.ServiceAccountKey
+		want   KeyStatus
+	}{
+		{"fresh", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(10 * day)}, KeyCompliant},
+		{"past warn age", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(80 * day)}, KeyWarning},
+		{"past max age", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(91 * day)}, KeyViolating},
+		{"no max age", KeyPolicy{}, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(1000 * day)}, KeyCompliant},
+		{"expired", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(10 * day), ValidBeforeTime: created(day)}, KeyWarning},
+		{"never expires", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(10 * day), ValidBeforeTime: "9999-12-31T23:59:59Z"}, KeyCompliant},
+		{"past max age and expired", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(100 * day), ValidBeforeTime: created(day)}, KeyViolating},
+		{"disabled", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(100 * day), Disabled: true}, KeySkipped},
+		{"disabled included", KeyPolicy{MaxAge: 90 * day, IncludeDisabled: true}, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: created(100 * day), Disabled: true}, KeyViolating},
+		{"system managed", policy, iam.ServiceAccountKey{KeyType: "SYSTEM_MANAGED", ValidAfterTime: created(100 * day)}, KeySkipped},
+		{"all key types", KeyPolicy{MaxAge: 90 * day}, iam.ServiceAccountKey{KeyType: "SYSTEM_MANAGED", ValidAfterTime: created(100 * day)}, KeyViolating},
+		{"unparseable creation time", policy, iam.ServiceAccountKey{KeyType: "USER_MANAGED", ValidAfterTime: "yesterday"}, KeyWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := tt.policy.evaluate(&tt.key, now)
+			if finding.Status != tt.want {
+				t.Errorf("evaluate() = %s (%s), want %s", finding.Status, finding.Reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/itsvictorfy/pkg/k8s"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// KeyRotationOptions configures RotateServiceAccountKey
+type KeyRotationOptions struct {
+	SecretName   string            // Secret Manager secret that receives the new key, required
+	CreateSecret bool              // Create the secret when it doesn't exist yet
+	SecretLabels map[string]string // Labels for a created secret
+
+	KubeClient     *k8s.KubeClient // Also publish the key to this cluster when set
+	KubeSecretName string
+	KubeNamespace  string
+	KubeSecretKey  string // Defaults to key.json
+
+	Policy      *KeyPolicy    // Previous keys violating this policy are deleted, defaults to DefaultKeyPolicy
+	GracePeriod time.Duration // Wait this long after publishing before deleting old keys, so consumers can reload
+	KeepOld     int           // Number of the newest previous keys to keep even if they are outdated
+	DryRun      bool          // Only report which keys would be deleted
+}
+
+// KeyRotationReport describes what RotateServiceAccountKey did, or would do in dry-run mode
+type KeyRotationReport struct {
+	ServiceAccount string
+	DryRun         bool
+	NewKey         string // ID of the created key
+	SecretVersion  string // Secret Manager version holding the new key
+	KubeSecret     string // namespace/name of the updated Kubernetes Secret
+	DeletedKeys    []string
+	KeptKeys       []string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+}
+
+// RotateServiceAccountKey creates a new user-managed key, publishes it to Secret Manager and optionally a Kubernetes
+// Secret, waits for the grace period and then deletes the previous user-managed keys that violate the policy. If
+// publishing fails the new key is deleted again, and if ctx is cancelled during the grace period the old keys are
+// left in place.
+func (gcp *Gcp) RotateServiceAccountKey(ctx context.Context, saEmail string, opts KeyRotationOptions) (*KeyRotationReport, error) {
+	report := &KeyRotationReport{ServiceAccount: saEmail, DryRun: opts.DryRun, StartedAt: time.Now()}
+	defer func() { report.FinishedAt = time.Now() }()
+	if opts.SecretName == "" {
+		return report, fmt.Errorf("gcp: key rotation for %s needs a secret name", saEmail)
+	}
+	if opts.KubeClient != nil && (opts.KubeSecretName == "" || opts.KubeNamespace == "") {
+		return report, fmt.Errorf("gcp: key rotation for %s needs a Kubernetes secret name and namespace", saEmail)
+	}
+	if opts.KubeSecretKey == "" {
+		opts.KubeSecretKey = "key.json"
+	}
+	policy := DefaultKeyPolicy
+	if opts.Policy != nil {
+		policy = *opts.Policy
+	}
+
+	keys, err := gcp.GetServiceAccountKeys(saEmail)
+	if err != nil {
+		return report, err
+	}
+	var oldKeys []*iam.ServiceAccountKey
+	for _, key := range keys {
+		if key.KeyType == "USER_MANAGED" {
+			oldKeys = append(oldKeys, key)
+		}
+	}
+	// Newest first, so the keys to keep come before the ones to delete
+	sort.Slice(oldKeys, func(i, j int) bool { return oldKeys[i].ValidAfterTime > oldKeys[j].ValidAfterTime })
+	expired := outdatedKeys(oldKeys, policy, opts.KeepOld, time.Now())
+	for _, key := range oldKeys {
+		if !slices.Contains(expired, key) {
+			report.KeptKeys = append(report.KeptKeys, path.Base(key.Name))
+		}
+	}
+
+	if opts.DryRun {
+		for _, key := range expired {
+			report.DeletedKeys = append(report.DeletedKeys, path.Base(key.Name))
+		}
+		slog.Info("GCP: key rotation dry run", slog.String("SaEmail", saEmail), slog.Int("WouldDelete", len(expired)))
+		return report, nil
+	}
+
+	key, err := gcp.CreateServiceAccountKey(saEmail)
+	if err != nil {
+		return report, err
+	}
+	report.NewKey = path.Base(key.Name)
+	keyJSON, err := base64.StdEncoding.DecodeString(key.PrivateKeyData)
+	if err == nil {
+		err = gcp.publishKey(ctx, string(keyJSON), opts, report)
+	}
+	if err != nil {
+		if deleteErr := gcp.DeleteServiceAccountKey(saEmail, key); deleteErr != nil {
+			return report, fmt.Errorf("gcp: unable to publish new key for %s: %v, and the new key %s could not be deleted: %v", saEmail, err, report.NewKey, deleteErr)
+		}
+		return report, fmt.Errorf("gcp: unable to publish new key for %s, it was deleted again: %v", saEmail, err)
+	}
+	slog.Info("GCP: new key published", slog.String("SaEmail", saEmail), slog.String("Key", report.NewKey), slog.String("Version", report.SecretVersion))
+
+	if opts.GracePeriod > 0 && len(expired) > 0 {
+		timer := time.NewTimer(opts.GracePeriod)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return report, fmt.Errorf("gcp: key rotation for %s stopped before deleting old keys: %v", saEmail, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	for _, old := range expired {
+		if err := gcp.DeleteServiceAccountKey(saEmail, old); err != nil {
+			return report, err
+		}
+		report.DeletedKeys = append(report.DeletedKeys, path.Base(old.Name))
+	}
+	slog.Info("GCP: key rotated", slog.String("SaEmail", saEmail), slog.String("Key", report.NewKey), slog.Int("Deleted", len(report.DeletedKeys)))
+	return report, nil
+}
+
+// outdatedKeys returns the keys violating the policy, keys must be sorted newest first and the keep newest are spared
+func outdatedKeys(keys []*iam.ServiceAccountKey, policy KeyPolicy, keep int, now time.Time) []*iam.ServiceAccountKey {
+	var outdated []*iam.ServiceAccountKey
+	for i, key := range keys {
+		if i >= keep && policy.evaluate(key, now).Status == KeyViolating {
+			outdated = append(outdated, key)
+		}
+	}
+	return outdated
+}
+
+// publishKey stores the key JSON in Secret Manager and, when configured, in the Kubernetes Secret
+func (gcp *Gcp) publishKey(ctx context.Context, keyJSON string, opts KeyRotationOptions, report *KeyRotationReport) error {
+	if opts.CreateSecret {
+		client, err := gcp.SecretManager()
+		if err != nil {
+			return err
+		}
+		_, err = client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: gcp.secretPath(opts.SecretName)})
+		if status.Code(err) == codes.NotFound {
+			err = gcp.CreateSecret(opts.SecretName, "", SecretOptions{Labels: opts.SecretLabels})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	version, err := gcp.AddSecretVersion(opts.SecretName, keyJSON)
+	if err != nil {
+		return err
+	}
+	report.SecretVersion = version
+
+	if opts.KubeClient == nil {
+		return nil
+	}
+	_, err = opts.KubeClient.UpsertSecret(ctx, opts.KubeSecretName, opts.KubeNamespace, k8s.ConfigData{
+		Data: map[string]string{opts.KubeSecretKey: keyJSON},
+	})
+	if err != nil {
+		return err
+	}
+	report.KubeSecret = opts.KubeNamespace + "/" + opts.KubeSecretName
+	return nil
+}
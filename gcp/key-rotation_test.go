@@ -0,0 +1,45 @@
+package gcp
+
+import (
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/iam/v1"
+)
+
+func TestOutdatedKeys(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	key := func(id string, age time.Duration) *iam.ServiceAccountKey {
+		return &iam.ServiceAccountKey{Name: "keys/" + id, KeyType: "USER_MANAGED", ValidAfterTime: now.Add(-age).Format(time.RFC3339)}
+	}
+	// Newest first, as RotateServiceAccountKey sorts them
+	keys := []*iam.ServiceAccountKey{
+		key("fresh", time.Hour),
+		key("old", 100*24*time.Hour),
+		key("older", 200*24*time.Hour),
+		{Name: "keys/unparseable", KeyType: "USER_MANAGED", ValidAfterTime: "yesterday"},
+	}
+
+	tests := []struct {
+		name string
+		keep int
+		want []string
+	}{
+		{"fresh keys survive", 0, []string{"old", "older"}},
+		{"keep spares the newest", 2, []string{"older"}},
+		{"keep beyond the keys", 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, k := range outdatedKeys(keys, DefaultKeyPolicy, tt.keep, now) {
+				got = append(got, path.Base(k.Name))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("outdatedKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+// SecretCacheOptions configures a SecretCache
+type SecretCacheOptions struct {
+	TTL             time.Duration // How long a value is served before checking for a new version, defaults to five minutes
+	RefreshInterval time.Duration // Check cached secrets in the background at this interval, 0 disables it
+}
+
+// SecretUpdate is sent to subscribers when a new version of a secret is seen
+type SecretUpdate struct {
+	Name    string
+	Version string
+	Value   string
+}
+
+// SecretCache serves secrets from memory and picks up new versions without restarts.
+// Checks only read the version metadata, the payload is fetched again when the version or its etag changed.
+type SecretCache struct {
+	gcp  *Gcp
+	opts SecretCacheOptions
+	stop context.CancelFunc
+
+	mu          sync.Mutex
+	entries     map[string]*secretEntry
+	refreshes   map[string]uint64 // Refreshes started per secret, orders overlapping refreshes
+	newest      map[string]int64  // Newest version seen per secret, kept by Invalidate so later versions are still sent
+	subscribers map[string]map[chan SecretUpdate]struct{}
+	closed      bool
+}
+
+type secretEntry struct {
+	value     string
+	version   string
+	etag      string
+	checkedAt time.Time
+	refresh   uint64 // Sequence number of the refresh that stored the entry
+}
+
+// NewSecretCache creates a cache on top of the Secret Manager client, call Close to stop the background refresh
+func (gcp *Gcp) NewSecretCache(opts SecretCacheOptions) *SecretCache {
+	if opts.TTL <= 0 {
+		opts.TTL = 5 * time.Minute
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	cache := &SecretCache{
+		gcp:         gcp,
+		opts:        opts,
+		stop:        stop,
+		entries:     map[string]*secretEntry{},
+		refreshes:   map[string]uint64{},
+		newest:      map[string]int64{},
+		subscribers: map[string]map[chan SecretUpdate]struct{}{},
+	}
+	if opts.RefreshInterval > 0 {
+		go cache.refreshLoop(ctx)
+	}
+	return cache
+}
+
+// Get returns the latest version of a secret, from memory while it is younger than the TTL.
+// If the check fails, the cached value is returned and the error is logged.
+func (s *SecretCache) Get(name string) (string, error) {
+	s.mu.Lock()
+	entry, cached := s.entries[name]
+	s.mu.Unlock()
+	if cached && time.Since(entry.checkedAt) < s.opts.TTL {
+		return entry.value, nil
+	}
+
+	refreshed, err := s.refresh(name)
+	if err != nil {
+		if cached {
+			slog.Warn("GCP: serving cached secret after failed refresh", slog.String("Secret", name), slog.String("Error", err.Error()))
+			return entry.value, nil
+		}
+		return "", err
+	}
+	return refreshed.value, nil
+}
+
+// Invalidate drops a secret from memory so the next Get fetches it again
+func (s *SecretCache) Invalidate(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, name)
+}
+
+// Subscribe returns a channel that receives every new version of the secret found by Get or the background refresh.
+// The current version is loaded first so only later versions are sent. Slow subscribers only get the newest update.
+// Call the returned function to unsubscribe, the channel is closed then or when the cache is closed.
+func (s *SecretCache) Subscribe(name string) (<-chan SecretUpdate, func(), error) {
+	if _, err := s.Get(name); err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan SecretUpdate, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, nil, fmt.Errorf("gcp: secret cache is closed")
+	}
+	if s.subscribers[name] == nil {
+		s.subscribers[name] = map[chan SecretUpdate]struct{}{}
+	}
+	s.subscribers[name][ch] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if _, ok := s.subscribers[name][ch]; ok {
+				delete(s.subscribers[name], ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, unsubscribe, nil
+}
+
+// Close stops the background refresh and closes all subscriber channels
+func (s *SecretCache) Close() {
+	s.stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for name, channels := range s.subscribers {
+		for ch := range channels {
+			close(ch)
+		}
+		delete(s.subscribers, name)
+	}
+}
+
+// refresh compares the latest version with the cached one and fetches the payload when it changed.
+// The cache always follows latest, which can move back when a version is disabled, but subscribers are
+// only sent versions newer than any seen before. When refreshes overlap, the one started last wins.
+func (s *SecretCache) refresh(name string) (*secretEntry, error) {
+	s.mu.Lock()
+	s.refreshes[name]++
+	seq := s.refreshes[name]
+	s.mu.Unlock()
+
+	latest, err := s.gcp.latestSecretVersion(name)
+	if err != nil {
+		return nil, err
+	}
+	version := path.Base(latest.Name)
+
+	s.mu.Lock()
+	current := s.entries[name]
+	if current != nil && current.refresh > seq {
+		s.mu.Unlock()
+		return current, nil
+	}
+	if current != nil && current.version == version && current.etag == latest.Etag {
+		checked := *current
+		checked.checkedAt = time.Now()
+		checked.refresh = seq
+		s.entries[name] = &checked
+		s.mu.Unlock()
+		return &checked, nil
+	}
+	s.mu.Unlock()
+
+	value, err := s.gcp.GetSecretVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	entry := &secretEntry{value: value, version: version, etag: latest.Etag, checkedAt: time.Now(), refresh: seq}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current := s.entries[name]; current != nil && current.refresh > seq {
+		return current, nil
+	}
+	s.entries[name] = entry
+	newest, seen := s.newest[name]
+	if number := versionNumber(version); !seen || number > newest {
+		s.newest[name] = number
+		if seen {
+			slog.Info("GCP: new secret version", slog.String("Secret", name), slog.String("Version", version))
+			s.notify(SecretUpdate{Name: name, Version: version, Value: value})
+		}
+	}
+	return entry, nil
+}
+
+// notify replaces any undelivered update with the new one, s.mu must be held
+func (s *SecretCache) notify(update SecretUpdate) {
+	for ch := range s.subscribers[update.Name] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- update
+	}
+}
+
+func (s *SecretCache) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		names := make([]string, 0, len(s.entries))
+		for name := range s.entries {
+			names = append(names, name)
+		}
+		s.mu.Unlock()
+		for _, name := range names {
+			if _, err := s.refresh(name); err != nil {
+				slog.Warn("GCP: unable to refresh secret", slog.String("Secret", name), slog.String("Error", err.Error()))
+			}
+		}
+	}
+}
+
+// versionNumber parses a Secret Manager version number, anything else counts as 0
+func versionNumber(version string) int64 {
+	number, _ := strconv.ParseInt(version, 10, 64)
+	return number
+}
+
+// latestSecretVersion reads the metadata of the latest version without accessing its payload
+func (gcp *Gcp) latestSecretVersion(name string) (*secretmanagerpb.SecretVersion, error) {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return nil, err
+	}
+	version, err := client.GetSecretVersion(context.Background(), &secretmanagerpb.GetSecretVersionRequest{
+		Name: gcp.secretVersionPath(name, "latest"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to get latest version of %s: %v", name, err)
+	}
+	return version, nil
+}
@@ -0,0 +1,239 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// fakeSecretManager serves the versions of one secret, latest can be moved back and forth.
+// Requests that change secrets or list versions are recorded.
+type fakeSecretManager struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+	mu       sync.Mutex
+	versions map[string]string
+	latest   string
+	requests []proto.Message
+}
+
+// record stores the request, f.mu must be held
+func (f *fakeSecretManager) record(req proto.Message) {
+	f.requests = append(f.requests, req)
+}
+
+func (f *fakeSecretManager) recorded() []proto.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.requests)
+}
+
+func (f *fakeSecretManager) setLatest(version, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions[version] = value
+	f.latest = version
+}
+
+func (f *fakeSecretManager) resolve(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if version := path.Base(name); version != "latest" {
+		return version
+	}
+	return f.latest
+}
+
+func (f *fakeSecretManager) GetSecretVersion(ctx context.Context, req *secretmanagerpb.GetSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	version := f.resolve(req.Name)
+	return &secretmanagerpb.SecretVersion{Name: path.Dir(req.Name) + "/" + version, Etag: "etag-" + version}, nil
+}
+
+func (f *fakeSecretManager) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	version := f.resolve(req.Name)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.versions[version]
+	if !ok {
+		return nil, fmt.Errorf("version %s not found", version)
+	}
+	return &secretmanagerpb.AccessSecretVersionResponse{Payload: &secretmanagerpb.SecretPayload{Data: []byte(value)}}, nil
+}
+
+func (f *fakeSecretManager) AddSecretVersion(ctx context.Context, req *secretmanagerpb.AddSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	version := strconv.Itoa(len(f.versions) + 1)
+	f.versions[version] = string(req.Payload.Data)
+	f.latest = version
+	return &secretmanagerpb.SecretVersion{Name: req.Parent + "/versions/" + version}, nil
+}
+
+func (f *fakeSecretManager) CreateSecret(ctx context.Context, req *secretmanagerpb.CreateSecretRequest) (*secretmanagerpb.Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	return &secretmanagerpb.Secret{Name: req.Parent + "/secrets/" + req.SecretId}, nil
+}
+
+func (f *fakeSecretManager) ListSecretVersions(ctx context.Context, req *secretmanagerpb.ListSecretVersionsRequest) (*secretmanagerpb.ListSecretVersionsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	response := &secretmanagerpb.ListSecretVersionsResponse{}
+	for number := len(f.versions); number > 0; number-- {
+		response.Versions = append(response.Versions, &secretmanagerpb.SecretVersion{Name: req.Parent + "/versions/" + strconv.Itoa(number)})
+	}
+	return response, nil
+}
+
+func (f *fakeSecretManager) DisableSecretVersion(ctx context.Context, req *secretmanagerpb.DisableSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	return &secretmanagerpb.SecretVersion{Name: req.Name, State: secretmanagerpb.SecretVersion_DISABLED}, nil
+}
+
+func (f *fakeSecretManager) EnableSecretVersion(ctx context.Context, req *secretmanagerpb.EnableSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	return &secretmanagerpb.SecretVersion{Name: req.Name, State: secretmanagerpb.SecretVersion_ENABLED}, nil
+}
+
+func (f *fakeSecretManager) DestroySecretVersion(ctx context.Context, req *secretmanagerpb.DestroySecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	return &secretmanagerpb.SecretVersion{Name: req.Name, State: secretmanagerpb.SecretVersion_DESTROYED}, nil
+}
+
+func (f *fakeSecretManager) DeleteSecret(ctx context.Context, req *secretmanagerpb.DeleteSecretRequest) (*emptypb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(req)
+	return &emptypb.Empty{}, nil
+}
+
+// newTestSecretManager returns a Gcp whose Secret Manager client talks to an in-process fake
+func newTestSecretManager(t *testing.T) (*Gcp, *fakeSecretManager) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	fake := &fakeSecretManager{versions: map[string]string{}}
+	server := grpc.NewServer()
+	secretmanagerpb.RegisterSecretManagerServiceServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	gcp := &Gcp{ProjectID: "test", options: []option.ClientOption{option.WithGRPCConn(conn)}}
+	t.Cleanup(func() { gcp.Close() })
+	return gcp, fake
+}
+
+func TestSecretCacheFollowsLatestBack(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+	cache := gcp.NewSecretCache(SecretCacheOptions{})
+	defer cache.Close()
+	fake.setLatest("3", "three")
+
+	updates, unsubscribe, err := cache.Subscribe("db")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer unsubscribe()
+
+	// Disabling version 3 moves latest back to 2, which is served but isn't an update
+	fake.setLatest("2", "two")
+	entry, err := cache.refresh("db")
+	if err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if entry.value != "two" {
+		t.Errorf("refresh() value = %q, want two", entry.value)
+	}
+	select {
+	case update := <-updates:
+		t.Errorf("subscriber got update to version %s, want none", update.Version)
+	default:
+	}
+}
+
+func TestSecretCacheNotifiesAfterInvalidate(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+	cache := gcp.NewSecretCache(SecretCacheOptions{})
+	defer cache.Close()
+	fake.setLatest("1", "one")
+
+	updates, unsubscribe, err := cache.Subscribe("db")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer unsubscribe()
+
+	cache.Invalidate("db")
+	fake.setLatest("2", "two")
+	if value, err := cache.Get("db"); err != nil || value != "two" {
+		t.Fatalf("Get() = %q, %v, want two", value, err)
+	}
+	select {
+	case update := <-updates:
+		if update.Version != "2" || update.Value != "two" {
+			t.Errorf("update = %+v, want version 2", update)
+		}
+	default:
+		t.Error("subscriber got no update for the version rotated after Invalidate")
+	}
+
+	// Loading the same version again after Invalidate is not an update
+	cache.Invalidate("db")
+	if _, err := cache.Get("db"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	select {
+	case update := <-updates:
+		t.Errorf("subscriber got repeated update %+v", update)
+	default:
+	}
+}
+
+func TestSecretCacheOverlappingRefresh(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+	cache := gcp.NewSecretCache(SecretCacheOptions{})
+	defer cache.Close()
+	fake.setLatest("1", "one")
+	if _, err := cache.Get("db"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// Pretend a refresh started later has already stored its result
+	cache.mu.Lock()
+	cache.entries["db"].refresh = cache.refreshes["db"] + 10
+	cache.mu.Unlock()
+
+	fake.setLatest("2", "two")
+	entry, err := cache.refresh("db")
+	if err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if entry.value != "one" {
+		t.Errorf("refresh() value = %q, want the newer refresh's one", entry.value)
+	}
+}
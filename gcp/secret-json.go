@@ -0,0 +1,230 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/itsvictorfy/pkg/k8s"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrSecretConflict is returned when a secret kept getting new versions while it was being updated
+var ErrSecretConflict = errors.New("gcp: secret changed during update")
+
+// Attempts made by UpdateSecretJSON before giving up with ErrSecretConflict
+const secretUpdateAttempts = 3
+
+// SecretExport selects a secret value to export under Key
+type SecretExport struct {
+	Key     string // Environment variable or Kubernetes Secret key
+	Secret  string // Secret Manager secret name
+	Version string // Defaults to latest
+	Field   string // Export a single top-level field of a JSON secret instead of the whole payload
+}
+
+// GetSecretJSON decodes the latest version of a JSON secret into T
+func GetSecretJSON[T any](gcp *Gcp, name string) (T, error) {
+	var value T
+	payload, err := gcp.GetSecret(name)
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal([]byte(payload), &value); err != nil {
+		return value, fmt.Errorf("gcp: secret %s is not valid JSON: %v", name, err)
+	}
+	return value, nil
+}
+
+// GetSecretYAML decodes the latest version of a YAML secret into T, using its json field tags
+func GetSecretYAML[T any](gcp *Gcp, name string) (T, error) {
+	var value T
+	payload, err := gcp.GetSecret(name)
+	if err != nil {
+		return value, err
+	}
+	if err := yaml.Unmarshal([]byte(payload), &value); err != nil {
+		return value, fmt.Errorf("gcp: secret %s is not valid YAML: %v", name, err)
+	}
+	return value, nil
+}
+
+// PutSecretJSON stores value as a new version of a JSON secret and returns the version number
+func (gcp *Gcp) PutSecretJSON(name string, value any) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("gcp: unable to encode secret %s: %v", name, err)
+	}
+	return gcp.AddSecretVersion(name, string(payload))
+}
+
+// UpdateSecretJSON reads the latest version of a JSON secret, lets update change it and writes it back as a new version.
+// The write is skipped and retried from the start when another version was added in the meantime, after a few
+// attempts ErrSecretConflict is returned. Secret Manager has no conditional writes, so a writer racing the final
+// check can still slip in between.
+func UpdateSecretJSON[T any](gcp *Gcp, name string, update func(*T) error) (string, error) {
+	for attempt := 0; attempt < secretUpdateAttempts; attempt++ {
+		latest, err := gcp.latestSecretVersion(name)
+		if err != nil {
+			return "", err
+		}
+		version := path.Base(latest.Name)
+		payload, err := gcp.GetSecretVersion(name, version)
+		if err != nil {
+			return "", err
+		}
+		var value T
+		if err := json.Unmarshal([]byte(payload), &value); err != nil {
+			return "", fmt.Errorf("gcp: secret %s is not valid JSON: %v", name, err)
+		}
+		if err := update(&value); err != nil {
+			return "", err
+		}
+
+		current, err := gcp.latestSecretVersion(name)
+		if err != nil {
+			return "", err
+		}
+		if path.Base(current.Name) != version {
+			slog.Warn("GCP: secret changed during update, retrying", slog.String("Secret", name), slog.String("Version", version))
+			continue
+		}
+		return gcp.PutSecretJSON(name, value)
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretConflict, name)
+}
+
+// UpdateSecretFields sets top-level fields of a JSON object secret, a nil value removes the field.
+// Fields that aren't updated are written back exactly as they were read, so large numbers keep their precision.
+func (gcp *Gcp) UpdateSecretFields(name string, fields map[string]any) (string, error) {
+	return UpdateSecretJSON(gcp, name, func(object *map[string]json.RawMessage) error {
+		if *object == nil {
+			*object = map[string]json.RawMessage{}
+		}
+		for field, value := range fields {
+			if value == nil {
+				delete(*object, field)
+				continue
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("gcp: unable to encode field %s of %s: %v", field, name, err)
+			}
+			(*object)[field] = encoded
+		}
+		return nil
+	})
+}
+
+// ExportSecretsToEnvFile writes the secrets to a .env file readable only by the owner, replacing it atomically
+func (gcp *Gcp) ExportSecretsToEnvFile(exports []SecretExport, filePath string) error {
+	values, err := gcp.resolveExports(exports)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, quoteEnvValue(values[key]))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".env-*")
+	if err != nil {
+		return fmt.Errorf("gcp: unable to write %s: %v", filePath, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("gcp: unable to write %s: %v", filePath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("gcp: unable to write %s: %v", filePath, err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("gcp: unable to write %s: %v", filePath, err)
+	}
+	slog.Info("GCP: secrets exported", slog.String("File", filePath), slog.Int("Keys", len(keys)))
+	return nil
+}
+
+// ExportSecretsToKubeSecret creates or updates a Kubernetes Secret holding the secrets
+func (gcp *Gcp) ExportSecretsToKubeSecret(ctx context.Context, kube *k8s.KubeClient, exports []SecretExport, secretName, namespace string) error {
+	values, err := gcp.resolveExports(exports)
+	if err != nil {
+		return err
+	}
+	_, err = kube.UpsertSecret(ctx, secretName, namespace, k8s.ConfigData{
+		Data:   values,
+		Labels: map[string]string{"app.kubernetes.io/managed-by": "gcp-secret-manager"},
+	})
+	if err != nil {
+		return fmt.Errorf("gcp: unable to export secrets to %s/%s: %v", namespace, secretName, err)
+	}
+	slog.Info("GCP: secrets exported", slog.String("Secret", namespace+"/"+secretName), slog.Int("Keys", len(values)))
+	return nil
+}
+
+// resolveExports reads every exported secret, fetching each version only once
+func (gcp *Gcp) resolveExports(exports []SecretExport) (map[string]string, error) {
+	payloads := map[string]string{}
+	values := make(map[string]string, len(exports))
+	for _, export := range exports {
+		version := export.Version
+		if version == "" {
+			version = "latest"
+		}
+		id := export.Secret + "/" + version
+		payload, ok := payloads[id]
+		if !ok {
+			var err error
+			payload, err = gcp.GetSecretVersion(export.Secret, version)
+			if err != nil {
+				return nil, err
+			}
+			payloads[id] = payload
+		}
+
+		if export.Field == "" {
+			values[export.Key] = payload
+			continue
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(payload), &object); err != nil {
+			return nil, fmt.Errorf("gcp: secret %s is not a JSON object: %v", export.Secret, err)
+		}
+		field, ok := object[export.Field]
+		if !ok {
+			return nil, fmt.Errorf("gcp: secret %s has no field %s", export.Secret, export.Field)
+		}
+		// Strings are exported unquoted, anything else as compact JSON with numbers kept as written
+		var s string
+		if err := json.Unmarshal(field, &s); err == nil {
+			values[export.Key] = s
+			continue
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, field); err != nil {
+			return nil, fmt.Errorf("gcp: secret %s has an invalid field %s: %v", export.Secret, export.Field, err)
+		}
+		values[export.Key] = compact.String()
+	}
+	return values, nil
+}
+
+// quoteEnvValue double quotes a value the way dotenv parsers read it back
+func quoteEnvValue(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
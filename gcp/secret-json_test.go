@@ -0,0 +1,78 @@
+package gcp
+
+import "testing"
+
+func TestQuoteEnvValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", `"plain"`},
+		{"", `""`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path`, `"C:\\path"`},
+		{"line1\nline2\r\n", `"line1\nline2\r\n"`},
+		{"$HOME", `"\$HOME"`},
+	}
+	for _, tt := range tests {
+		if got := quoteEnvValue(tt.value); got != tt.want {
+			t.Errorf("quoteEnvValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveExports(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+	fake.setLatest("1", "not json")
+	fake.setLatest("2", `{"user":"app","port":5432,"account":9007199254740993,"big":1000000000000000000000,"tls": {"enabled": true}}`)
+
+	values, err := gcp.resolveExports([]SecretExport{
+		{Key: "DB_CONFIG", Secret: "db"},
+		{Key: "DB_USER", Secret: "db", Field: "user"},
+		{Key: "DB_PORT", Secret: "db", Version: "2", Field: "port"},
+		{Key: "DB_ACCOUNT", Secret: "db", Field: "account"},
+		{Key: "DB_BIG", Secret: "db", Field: "big"},
+		{Key: "DB_TLS", Secret: "db", Field: "tls"},
+		{Key: "DB_OLD", Secret: "db", Version: "1"},
+	})
+	if err != nil {
+		t.Fatalf("resolveExports() error = %v", err)
+	}
+	want := map[string]string{
+		"DB_CONFIG":  `{"user":"app","port":5432,"account":9007199254740993,"big":1000000000000000000000,"tls": {"enabled": true}}`,
+		"DB_USER":    "app",
+		"DB_PORT":    "5432",
+		"DB_ACCOUNT": "9007199254740993",
+		"DB_BIG":     "1000000000000000000000",
+		"DB_TLS":     `{"enabled":true}`,
+		"DB_OLD":     "not json",
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %q, want %q", key, values[key], value)
+		}
+	}
+
+	if _, err := gcp.resolveExports([]SecretExport{{Key: "X", Secret: "db", Field: "password"}}); err == nil {
+		t.Error("resolveExports() expected error for a missing field")
+	}
+	if _, err := gcp.resolveExports([]SecretExport{{Key: "X", Secret: "db", Version: "1", Field: "user"}}); err == nil {
+		t.Error("resolveExports() expected error for a field of a non-JSON secret")
+	}
+}
+
+func TestUpdateSecretFields(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+	fake.setLatest("1", `{"account":9007199254740993,"user":"app","old":true}`)
+
+	if _, err := gcp.UpdateSecretFields("db", map[string]any{"user": "web", "old": nil}); err != nil {
+		t.Fatalf("UpdateSecretFields() error = %v", err)
+	}
+	got, err := gcp.GetSecret("db")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if want := `{"account":9007199254740993,"user":"web"}`; got != want {
+		t.Errorf("secret = %s, want %s", got, want)
+	}
+}
@@ -3,52 +3,240 @@ package gcp
 import (
 	"context"
 	"fmt"
+	"hash/crc32"
+	"log/slog"
+	"path"
+	"time"
 
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// SecretOptions configures a secret created by CreateSecret
+type SecretOptions struct {
+	Locations  []string          // User-managed replication to these locations, empty replicates automatically
+	Labels     map[string]string // Secret labels
+	TTL        time.Duration     // Delete the secret after this long, exclusive with ExpireTime
+	ExpireTime time.Time         // Delete the secret at this time
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
 // Retrieve the secret from the secret manager
 func (gcp *Gcp) GetSecret(name string) (string, error) {
-	secretName := "projects/" + gcp.ProjectID + "/secrets/" + name + "/versions/latest"
+	return gcp.GetSecretVersion(name, "latest")
+}
+
+// Retrieve a version of the secret, either a version number or latest
+func (gcp *Gcp) GetSecretVersion(name, version string) (string, error) {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return "", err
+	}
 	req := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: secretName,
+		Name: gcp.secretVersionPath(name, version),
 	}
-	result, err := gcp.SecretManagerService.AccessSecretVersion(context.Background(), req)
+	result, err := client.AccessSecretVersion(context.Background(), req)
 	if err != nil {
 		return "", fmt.Errorf("gcp: unable to get secret: %v, Error:%v", name, err)
 	}
-	secretData := string(result.Payload.Data)
-	return secretData, nil
+	if result.Payload.DataCrc32C != nil && int64(crc32.Checksum(result.Payload.Data, crc32c)) != *result.Payload.DataCrc32C {
+		return "", fmt.Errorf("gcp: secret %s version %s failed its checksum", name, version)
+	}
+	return string(result.Payload.Data), nil
 }
 
-// Update the secret in the secret manager
+// Update the secret in the secret manager by adding a new version
 func (gcp *Gcp) UpdateSecret(name, secretPayload string) error {
-	secretName := "projects/" + gcp.ProjectID + "/secrets/" + name + "/versions/latest"
+	_, err := gcp.AddSecretVersion(name, secretPayload)
+	return err
+}
+
+// Add a new version to the secret and return its version number
+func (gcp *Gcp) AddSecretVersion(name, secretPayload string) (string, error) {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return "", err
+	}
+	data := []byte(secretPayload)
+	checksum := int64(crc32.Checksum(data, crc32c))
 	req := &secretmanagerpb.AddSecretVersionRequest{
-		Parent: secretName,
+		Parent: gcp.secretPath(name),
 		Payload: &secretmanagerpb.SecretPayload{
-			Data: []byte(secretPayload),
+			Data:       data,
+			DataCrc32C: &checksum,
 		},
 	}
-	_, err := gcp.SecretManagerService.AddSecretVersion(context.Background(), req)
+	version, err := client.AddSecretVersion(context.Background(), req)
 	if err != nil {
-		return fmt.Errorf("gcp: unable to update secret: %v, Error: %v", name, err)
+		return "", fmt.Errorf("gcp: unable to update secret: %v, Error: %v", name, err)
 	}
-	return nil
-
+	slog.Info("GCP: secret version added", slog.String("Secret", name), slog.String("Version", path.Base(version.Name)))
+	return path.Base(version.Name), nil
 }
 
-// Create a new secret in the secret manager
-func (gcp *Gcp) CreateSecret(name, secretPayload string) error {
-	newSecretReq := &secretmanagerpb.AddSecretVersionRequest{
-		Parent: "projects/" + gcp.ProjectID + "/secrets/website-maintenance-config",
-		Payload: &secretmanagerpb.SecretPayload{
-			Data: []byte(secretPayload),
+// Create a new secret in the secret manager, adding the payload as its first version when it isn't empty
+func (gcp *Gcp) CreateSecret(name, secretPayload string, opts SecretOptions) error {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return err
+	}
+	secret := &secretmanagerpb.Secret{
+		Labels: opts.Labels,
+		Replication: &secretmanagerpb.Replication{
+			Replication: &secretmanagerpb.Replication_Automatic_{Automatic: &secretmanagerpb.Replication_Automatic{}},
 		},
 	}
-	_, err := gcp.SecretManagerService.AddSecretVersion(context.TODO(), newSecretReq)
+	if len(opts.Locations) > 0 {
+		replicas := make([]*secretmanagerpb.Replication_UserManaged_Replica, 0, len(opts.Locations))
+		for _, location := range opts.Locations {
+			replicas = append(replicas, &secretmanagerpb.Replication_UserManaged_Replica{Location: location})
+		}
+		secret.Replication.Replication = &secretmanagerpb.Replication_UserManaged_{
+			UserManaged: &secretmanagerpb.Replication_UserManaged{Replicas: replicas},
+		}
+	}
+	switch {
+	case opts.TTL > 0 && !opts.ExpireTime.IsZero():
+		return fmt.Errorf("gcp: secret %s can't have both a TTL and an expire time", name)
+	case opts.TTL > 0:
+		secret.Expiration = &secretmanagerpb.Secret_Ttl{Ttl: durationpb.New(opts.TTL)}
+	case !opts.ExpireTime.IsZero():
+		secret.Expiration = &secretmanagerpb.Secret_ExpireTime{ExpireTime: timestamppb.New(opts.ExpireTime)}
+	}
+
+	_, err = client.CreateSecret(context.Background(), &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/" + gcp.ProjectID,
+		SecretId: name,
+		Secret:   secret,
+	})
 	if err != nil {
 		return fmt.Errorf("gcp: unable to create secret: %v, Error: %v", name, err)
 	}
+	slog.Info("GCP: secret created", slog.String("Secret", name), slog.String("Env", gcp.Env))
+	if secretPayload == "" {
+		return nil
+	}
+	_, err = gcp.AddSecretVersion(name, secretPayload)
+	return err
+}
+
+// List the secrets of the project, filter uses the Secret Manager syntax, e.g. labels.team=web
+func (gcp *Gcp) ListSecrets(filter string) ([]*secretmanagerpb.Secret, error) {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return nil, err
+	}
+	it := client.ListSecrets(context.Background(), &secretmanagerpb.ListSecretsRequest{
+		Parent: "projects/" + gcp.ProjectID,
+		Filter: filter,
+	})
+	var secrets []*secretmanagerpb.Secret
+	for {
+		secret, err := it.Next()
+		if err == iterator.Done {
+			return secrets, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gcp: unable to list secrets: %v", err)
+		}
+		secrets = append(secrets, secret)
+	}
+}
+
+// List the versions of a secret, newest first, filter uses the Secret Manager syntax, e.g. state:ENABLED
+func (gcp *Gcp) ListVersions(name, filter string) ([]*secretmanagerpb.SecretVersion, error) {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return nil, err
+	}
+	it := client.ListSecretVersions(context.Background(), &secretmanagerpb.ListSecretVersionsRequest{
+		Parent: gcp.secretPath(name),
+		Filter: filter,
+	})
+	var versions []*secretmanagerpb.SecretVersion
+	for {
+		version, err := it.Next()
+		if err == iterator.Done {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gcp: unable to list versions of %s: %v", name, err)
+		}
+		versions = append(versions, version)
+	}
+}
+
+// Disable a version so it can no longer be accessed, it can be enabled again
+func (gcp *Gcp) DisableVersion(name, version string) error {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return err
+	}
+	_, err = client.DisableSecretVersion(context.Background(), &secretmanagerpb.DisableSecretVersionRequest{
+		Name: gcp.secretVersionPath(name, version),
+	})
+	if err != nil {
+		return fmt.Errorf("gcp: unable to disable %s version %s: %v", name, version, err)
+	}
+	slog.Info("GCP: secret version disabled", slog.String("Secret", name), slog.String("Version", version))
+	return nil
+}
+
+// Enable a disabled version
+func (gcp *Gcp) EnableVersion(name, version string) error {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return err
+	}
+	_, err = client.EnableSecretVersion(context.Background(), &secretmanagerpb.EnableSecretVersionRequest{
+		Name: gcp.secretVersionPath(name, version),
+	})
+	if err != nil {
+		return fmt.Errorf("gcp: unable to enable %s version %s: %v", name, version, err)
+	}
+	slog.Info("GCP: secret version enabled", slog.String("Secret", name), slog.String("Version", version))
 	return nil
 }
+
+// Destroy a version, its payload is deleted permanently
+func (gcp *Gcp) DestroyVersion(name, version string) error {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return err
+	}
+	_, err = client.DestroySecretVersion(context.Background(), &secretmanagerpb.DestroySecretVersionRequest{
+		Name: gcp.secretVersionPath(name, version),
+	})
+	if err != nil {
+		return fmt.Errorf("gcp: unable to destroy %s version %s: %v", name, version, err)
+	}
+	slog.Info("GCP: secret version destroyed", slog.String("Secret", name), slog.String("Version", version))
+	return nil
+}
+
+// Delete a secret with all of its versions
+func (gcp *Gcp) DeleteSecret(name string) error {
+	client, err := gcp.SecretManager()
+	if err != nil {
+		return err
+	}
+	err = client.DeleteSecret(context.Background(), &secretmanagerpb.DeleteSecretRequest{
+		Name: gcp.secretPath(name),
+	})
+	if err != nil {
+		return fmt.Errorf("gcp: unable to delete secret %s: %v", name, err)
+	}
+	slog.Info("GCP: secret deleted", slog.String("Secret", name), slog.String("Env", gcp.Env))
+	return nil
+}
+
+func (gcp *Gcp) secretPath(name string) string {
+	return "projects/" + gcp.ProjectID + "/secrets/" + name
+}
+
+func (gcp *Gcp) secretVersionPath(name, version string) string {
+	return gcp.secretPath(name) + "/versions/" + version
+}
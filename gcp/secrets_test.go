@@ -0,0 +1,136 @@
+package gcp
+
+import (
+	"hash/crc32"
+	"slices"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+func TestCreateSecret(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+
+	err := gcp.CreateSecret("db", "hunter2", SecretOptions{
+		Locations: []string{"europe-west1", "europe-west4"},
+		Labels:    map[string]string{"team": "web"},
+		TTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("CreateSecret() error = %v", err)
+	}
+	requests := fake.recorded()
+	if len(requests) != 2 {
+		t.Fatalf("CreateSecret() sent %d requests, want create and add version", len(requests))
+	}
+
+	create := requests[0].(*secretmanagerpb.CreateSecretRequest)
+	if create.Parent != "projects/test" || create.SecretId != "db" || create.Secret.Labels["team"] != "web" {
+		t.Errorf("create request = %v", create)
+	}
+	var locations []string
+	for _, replica := range create.Secret.Replication.GetUserManaged().GetReplicas() {
+		locations = append(locations, replica.Location)
+	}
+	if !slices.Equal(locations, []string{"europe-west1", "europe-west4"}) {
+		t.Errorf("replica locations = %v", locations)
+	}
+	if ttl := create.Secret.GetTtl().AsDuration(); ttl != time.Hour {
+		t.Errorf("ttl = %s, want 1h", ttl)
+	}
+
+	add := requests[1].(*secretmanagerpb.AddSecretVersionRequest)
+	if add.Parent != "projects/test/secrets/db" || string(add.Payload.Data) != "hunter2" {
+		t.Errorf("add version request = %v", add)
+	}
+	if want := int64(crc32.Checksum([]byte("hunter2"), crc32c)); add.Payload.GetDataCrc32C() != want {
+		t.Errorf("checksum = %d, want %d", add.Payload.GetDataCrc32C(), want)
+	}
+}
+
+func TestCreateSecretOptions(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+
+	if err := gcp.CreateSecret("db", "", SecretOptions{}); err != nil {
+		t.Fatalf("CreateSecret() error = %v", err)
+	}
+	requests := fake.recorded()
+	if len(requests) != 1 {
+		t.Fatalf("CreateSecret() without payload sent %d requests, want 1", len(requests))
+	}
+	secret := requests[0].(*secretmanagerpb.CreateSecretRequest).Secret
+	if secret.Replication.GetAutomatic() == nil || secret.Expiration != nil {
+		t.Errorf("secret = %v, want automatic replication and no expiration", secret)
+	}
+
+	expire := time.Now().Add(time.Hour)
+	if err := gcp.CreateSecret("db", "", SecretOptions{ExpireTime: expire}); err != nil {
+		t.Fatalf("CreateSecret() error = %v", err)
+	}
+	secret = fake.recorded()[1].(*secretmanagerpb.CreateSecretRequest).Secret
+	if !secret.GetExpireTime().AsTime().Equal(expire) {
+		t.Errorf("expire time = %v, want %v", secret.GetExpireTime().AsTime(), expire)
+	}
+
+	if err := gcp.CreateSecret("db", "", SecretOptions{TTL: time.Hour, ExpireTime: expire}); err == nil {
+		t.Error("CreateSecret() expected error for both a TTL and an expire time")
+	}
+	if len(fake.recorded()) != 2 {
+		t.Error("CreateSecret() sent a request for invalid options")
+	}
+}
+
+func TestSecretVersions(t *testing.T) {
+	gcp, fake := newTestSecretManager(t)
+	fake.setLatest("1", "one")
+	fake.setLatest("2", "two")
+
+	versions, err := gcp.ListVersions("db", "state:ENABLED")
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].Name != "projects/test/secrets/db/versions/2" {
+		t.Errorf("ListVersions() = %v, want versions 2 and 1", versions)
+	}
+
+	for name, call := range map[string]func(string, string) error{
+		"DisableVersion": gcp.DisableVersion,
+		"EnableVersion":  gcp.EnableVersion,
+		"DestroyVersion": gcp.DestroyVersion,
+	} {
+		if err := call("db", "1"); err != nil {
+			t.Errorf("%s() error = %v", name, err)
+		}
+	}
+	if err := gcp.DeleteSecret("db"); err != nil {
+		t.Errorf("DeleteSecret() error = %v", err)
+	}
+
+	var got []string
+	for _, req := range fake.recorded() {
+		switch req := req.(type) {
+		case *secretmanagerpb.ListSecretVersionsRequest:
+			got = append(got, "list "+req.Parent+" "+req.Filter)
+		case *secretmanagerpb.DisableSecretVersionRequest:
+			got = append(got, "disable "+req.Name)
+		case *secretmanagerpb.EnableSecretVersionRequest:
+			got = append(got, "enable "+req.Name)
+		case *secretmanagerpb.DestroySecretVersionRequest:
+			got = append(got, "destroy "+req.Name)
+		case *secretmanagerpb.DeleteSecretRequest:
+			got = append(got, "delete "+req.Name)
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		"delete projects/test/secrets/db",
+		"destroy projects/test/secrets/db/versions/1",
+		"disable projects/test/secrets/db/versions/1",
+		"enable projects/test/secrets/db/versions/1",
+		"list projects/test/secrets/db state:ENABLED",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+}
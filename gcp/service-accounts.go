@@ -1,9 +1,9 @@
 package gcp
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"time"
 
 	"google.golang.org/api/iam/v1"
 )
@@ -11,8 +11,12 @@ import (
 // Retrive all the keys for a service account
 func (gcp *Gcp) GetServiceAccountKeys(saEmail string) ([]*iam.ServiceAccountKey, error) { //V
 	slog.Info("GCP: retrieving Keys", slog.String("SaEmail", saEmail))
-	resource := "projects/-/serviceAccounts/" + saEmail
-	response, err := gcp.IamService.Projects.ServiceAccounts.Keys.List(resource).Do()
+	resource := serviceAccountPath(saEmail)
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return nil, err
+	}
+	response, err := iamService.Projects.ServiceAccounts.Keys.List(resource).Do()
 	if err != nil {
 		return nil, fmt.Errorf("gcp: unable to retrieve keys for %s: %v", saEmail, err)
 	}
@@ -20,23 +24,39 @@ func (gcp *Gcp) GetServiceAccountKeys(saEmail string) ([]*iam.ServiceAccountKey,
 	return response.Keys, nil
 }
 
-// Retrieve all the service accounts
+// Retrieve all the service accounts, following every page of the listing
 func (gcp *Gcp) GetAllServiceAccounts() ([]*iam.ServiceAccount, error) { //V
-	response, err := gcp.IamService.Projects.ServiceAccounts.List("projects/" + gcp.ProjectID).Do()
+	return gcp.listServiceAccounts(context.Background())
+}
+
+func (gcp *Gcp) listServiceAccounts(ctx context.Context) ([]*iam.ServiceAccount, error) {
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return nil, err
+	}
+	var accounts []*iam.ServiceAccount
+	err = iamService.Projects.ServiceAccounts.List("projects/"+gcp.ProjectID).Pages(ctx, func(page *iam.ListServiceAccountsResponse) error {
+		accounts = append(accounts, page.Accounts...)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("gcp: unable to retrieve service accounts: %v", err)
 	}
-	return response.Accounts, nil
+	return accounts, nil
 }
 
 // Create a service account
 func (gcp *Gcp) CreateServiceAccountKey(saEmail string) (*iam.ServiceAccountKey, error) { //V
-	resource := "projects/-/serviceAccounts/" + saEmail
+	resource := serviceAccountPath(saEmail)
 	request := &iam.CreateServiceAccountKeyRequest{
 		KeyAlgorithm:   "KEY_ALG_RSA_2048",
 		PrivateKeyType: "TYPE_GOOGLE_CREDENTIALS_FILE",
 	}
-	key, err := gcp.IamService.Projects.ServiceAccounts.Keys.Create(resource, request).Do()
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return nil, err
+	}
+	key, err := iamService.Projects.ServiceAccounts.Keys.Create(resource, request).Do()
 	if err != nil {
 		return nil, fmt.Errorf("gcp: unable to create key for: %w", err)
 	}
@@ -45,22 +65,100 @@ func (gcp *Gcp) CreateServiceAccountKey(saEmail string) (*iam.ServiceAccountKey,
 
 // Delete a service account key
 func (gcp *Gcp) DeleteServiceAccountKey(saEmail string, key *iam.ServiceAccountKey) error { //V
-	_, err := gcp.IamService.Projects.ServiceAccounts.Keys.Delete(key.Name).Do()
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return err
+	}
+	_, err = iamService.Projects.ServiceAccounts.Keys.Delete(key.Name).Do()
 	if err != nil {
 		return fmt.Errorf("gcp: unable to delete %s key: %v", saEmail, err)
 	}
 	return nil
 }
 
-// Check if a key is outdated
+// Check if a key violates DefaultKeyPolicy, use KeyPolicy.Evaluate for other policies
 func IsKeyOutdated(key *iam.ServiceAccountKey) bool { //V
-	slog.Info("GCP: Checking if Key is Outdated", slog.String("keyName", key.Name))
-	parsedTime, err := time.Parse(time.RFC3339, key.ValidAfterTime)
+	finding := DefaultKeyPolicy.Evaluate(key)
+	slog.Info("GCP:", slog.String("keyName", key.Name), slog.Bool("Key Outdated", finding.Status == KeyViolating), slog.String("Reason", finding.Reason))
+	return finding.Status == KeyViolating
+}
+
+// Create a service account, accountID is the part of the email before the @
+func (gcp *Gcp) CreateServiceAccount(accountID, displayName, description string) (*iam.ServiceAccount, error) {
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return nil, err
+	}
+	request := &iam.CreateServiceAccountRequest{
+		AccountId: accountID,
+		ServiceAccount: &iam.ServiceAccount{
+			DisplayName: displayName,
+			Description: description,
+		},
+	}
+	account, err := iamService.Projects.ServiceAccounts.Create("projects/"+gcp.ProjectID, request).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to create service account %s: %v", accountID, err)
+	}
+	slog.Info("GCP: service account created", slog.String("SaEmail", account.Email), slog.String("Env", gcp.Env))
+	return account, nil
+}
+
+// Retrieve a service account
+func (gcp *Gcp) GetServiceAccount(saEmail string) (*iam.ServiceAccount, error) {
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return nil, err
+	}
+	account, err := iamService.Projects.ServiceAccounts.Get(serviceAccountPath(saEmail)).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to retrieve service account %s: %v", saEmail, err)
+	}
+	return account, nil
+}
+
+// Disable a service account, its keys and tokens stop working until it is enabled again
+func (gcp *Gcp) DisableServiceAccount(saEmail string) error {
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return err
+	}
+	_, err = iamService.Projects.ServiceAccounts.Disable(serviceAccountPath(saEmail), &iam.DisableServiceAccountRequest{}).Do()
+	if err != nil {
+		return fmt.Errorf("gcp: unable to disable service account %s: %v", saEmail, err)
+	}
+	slog.Info("GCP: service account disabled", slog.String("SaEmail", saEmail), slog.String("Env", gcp.Env))
+	return nil
+}
+
+// Enable a disabled service account
+func (gcp *Gcp) EnableServiceAccount(saEmail string) error {
+	iamService, err := gcp.IAM()
+	if err != nil {
+		return err
+	}
+	_, err = iamService.Projects.ServiceAccounts.Enable(serviceAccountPath(saEmail), &iam.EnableServiceAccountRequest{}).Do()
+	if err != nil {
+		return fmt.Errorf("gcp: unable to enable service account %s: %v", saEmail, err)
+	}
+	slog.Info("GCP: service account enabled", slog.String("SaEmail", saEmail), slog.String("Env", gcp.Env))
+	return nil
+}
+
+// Delete a service account, its bindings remain in IAM policies as deleted members until removed
+func (gcp *Gcp) DeleteServiceAccount(saEmail string) error {
+	iamService, err := gcp.IAM()
 	if err != nil {
-		slog.Error("GCP: Error parsing ValidAfterTime", slog.String("keyName", key.Name), slog.String("Error:", err.Error()))
-		return false
+		return err
 	}
-	keyAge := time.Since(parsedTime)
-	slog.Info("GCP:", slog.String("keyName", key.Name), slog.Bool("Key Outdated", keyAge > (90*24*time.Hour)))
-	return keyAge > (90 * 24 * time.Hour)
+	_, err = iamService.Projects.ServiceAccounts.Delete(serviceAccountPath(saEmail)).Do()
+	if err != nil {
+		return fmt.Errorf("gcp: unable to delete service account %s: %v", saEmail, err)
+	}
+	slog.Info("GCP: service account deleted", slog.String("SaEmail", saEmail), slog.String("Env", gcp.Env))
+	return nil
+}
+
+func serviceAccountPath(saEmail string) string {
+	return "projects/-/serviceAccounts/" + saEmail
 }
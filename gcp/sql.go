@@ -0,0 +1,186 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// How often WaitForSQLOperation checks the operation
+const sqlOperationPollInterval = 5 * time.Second
+
+// SQLExportOptions configures ExportSQLDatabase
+type SQLExportOptions struct {
+	Databases []string // Databases to export, empty exports all of them for MySQL, PostgreSQL needs exactly one
+	FileType  string   // SQL or CSV, defaults to SQL
+	Query     string   // Select query producing the rows of a CSV export, required for CSV
+	Offload   bool     // Run the export on a temporary instance to keep load off the source
+}
+
+// SQLImportOptions configures ImportSQLDatabase
+type SQLImportOptions struct {
+	FileType string   // SQL or CSV, defaults to SQL
+	Table    string   // Table receiving a CSV import, required for CSV
+	Columns  []string // Columns of a CSV import, empty uses the table's columns in order
+	User     string   // Database user running the import, defaults to the instance's default user
+}
+
+// List the Cloud SQL instances of the project, following every page of the listing
+func (gcp *Gcp) ListSQLInstances() ([]*sqladmin.DatabaseInstance, error) {
+	sqlService, err := gcp.SQL()
+	if err != nil {
+		return nil, err
+	}
+	var instances []*sqladmin.DatabaseInstance
+	err = sqlService.Instances.List(gcp.ProjectID).Pages(context.Background(), func(page *sqladmin.InstancesListResponse) error {
+		instances = append(instances, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to list SQL instances: %v", err)
+	}
+	return instances, nil
+}
+
+// Start an on-demand backup of an instance, pass the operation to WaitForSQLOperation to wait for it
+func (gcp *Gcp) BackupSQLInstance(instance, description string) (*sqladmin.Operation, error) {
+	sqlService, err := gcp.SQL()
+	if err != nil {
+		return nil, err
+	}
+	op, err := sqlService.BackupRuns.Insert(gcp.ProjectID, instance, &sqladmin.BackupRun{Description: description}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to back up SQL instance %s: %v", instance, err)
+	}
+	slog.Info("GCP: SQL backup started", slog.String("Instance", instance), slog.String("Operation", op.Name))
+	return op, nil
+}
+
+// Start exporting databases of an instance to a gs://bucket/object URI, the instance's service account needs write access
+func (gcp *Gcp) ExportSQLDatabase(instance, gcsURI string, opts SQLExportOptions) (*sqladmin.Operation, error) {
+	sqlService, err := gcp.SQL()
+	if err != nil {
+		return nil, err
+	}
+	export := &sqladmin.ExportContext{
+		Uri:       gcsURI,
+		Databases: opts.Databases,
+		FileType:  opts.FileType,
+		Offload:   opts.Offload,
+	}
+	if export.FileType == "" {
+		export.FileType = "SQL"
+	}
+	if export.FileType == "CSV" {
+		if opts.Query == "" {
+			return nil, fmt.Errorf("gcp: CSV export of SQL instance %s needs a query", instance)
+		}
+		export.CsvExportOptions = &sqladmin.ExportContextCsvExportOptions{SelectQuery: opts.Query}
+	}
+	op, err := sqlService.Instances.Export(gcp.ProjectID, instance, &sqladmin.InstancesExportRequest{ExportContext: export}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to export SQL instance %s: %v", instance, err)
+	}
+	slog.Info("GCP: SQL export started", slog.String("Instance", instance), slog.String("Uri", gcsURI), slog.String("Operation", op.Name))
+	return op, nil
+}
+
+// Start importing a gs://bucket/object URI into a database, the instance's service account needs read access
+func (gcp *Gcp) ImportSQLDatabase(instance, database, gcsURI string, opts SQLImportOptions) (*sqladmin.Operation, error) {
+	sqlService, err := gcp.SQL()
+	if err != nil {
+		return nil, err
+	}
+	imp := &sqladmin.ImportContext{
+		Uri:        gcsURI,
+		Database:   database,
+		FileType:   opts.FileType,
+		ImportUser: opts.User,
+	}
+	if imp.FileType == "" {
+		imp.FileType = "SQL"
+	}
+	if imp.FileType == "CSV" {
+		if opts.Table == "" {
+			return nil, fmt.Errorf("gcp: CSV import into SQL instance %s needs a table", instance)
+		}
+		imp.CsvImportOptions = &sqladmin.ImportContextCsvImportOptions{Table: opts.Table, Columns: opts.Columns}
+	}
+	op, err := sqlService.Instances.Import(gcp.ProjectID, instance, &sqladmin.InstancesImportRequest{ImportContext: imp}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to import into SQL instance %s: %v", instance, err)
+	}
+	slog.Info("GCP: SQL import started", slog.String("Instance", instance), slog.String("Uri", gcsURI), slog.String("Operation", op.Name))
+	return op, nil
+}
+
+// Restart an instance, it is unavailable until the operation is done
+func (gcp *Gcp) RestartSQLInstance(instance string) (*sqladmin.Operation, error) {
+	sqlService, err := gcp.SQL()
+	if err != nil {
+		return nil, err
+	}
+	op, err := sqlService.Instances.Restart(gcp.ProjectID, instance).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: unable to restart SQL instance %s: %v", instance, err)
+	}
+	slog.Info("GCP: SQL restart started", slog.String("Instance", instance), slog.String("Operation", op.Name))
+	return op, nil
+}
+
+// WaitForSQLOperation polls an operation until it is done and returns its errors, if any.
+// A timeout of 0 waits as long as ctx allows. The operation keeps running on the server when waiting stops.
+func (gcp *Gcp) WaitForSQLOperation(ctx context.Context, op *sqladmin.Operation, timeout time.Duration) (*sqladmin.Operation, error) {
+	sqlService, err := gcp.SQL()
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	started := time.Now()
+	for {
+		current, err := sqlService.Operations.Get(gcp.ProjectID, op.Name).Context(ctx).Do()
+		if err != nil {
+			if ctx.Err() != nil {
+				return op, fmt.Errorf("gcp: SQL operation %s did not finish in time: %v", op.Name, ctx.Err())
+			}
+			return op, fmt.Errorf("gcp: unable to get SQL operation %s: %v", op.Name, err)
+		}
+		op = current
+		if op.Status == "DONE" {
+			if err := sqlOperationError(op); err != nil {
+				return op, err
+			}
+			slog.Info("GCP: SQL operation done", slog.String("Operation", op.Name), slog.String("Type", op.OperationType),
+				slog.String("Instance", op.TargetId), slog.Duration("Elapsed", time.Since(started)))
+			return op, nil
+		}
+		slog.Info("GCP: waiting for SQL operation", slog.String("Operation", op.Name), slog.String("Type", op.OperationType),
+			slog.String("Instance", op.TargetId), slog.String("Status", op.Status), slog.Duration("Elapsed", time.Since(started)))
+
+		select {
+		case <-ctx.Done():
+			return op, fmt.Errorf("gcp: SQL operation %s did not finish in time: %v", op.Name, ctx.Err())
+		case <-time.After(sqlOperationPollInterval):
+		}
+	}
+}
+
+// sqlOperationError joins the errors reported by a finished operation
+func sqlOperationError(op *sqladmin.Operation) error {
+	if op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(op.Error.Errors))
+	for _, opErr := range op.Error.Errors {
+		errs = append(errs, fmt.Errorf("%s: %s", opErr.Code, opErr.Message))
+	}
+	return fmt.Errorf("gcp: SQL operation %s %s failed: %v", op.OperationType, op.Name, errors.Join(errs...))
+}
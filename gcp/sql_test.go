@@ -0,0 +1,131 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// newTestSQL returns a Gcp whose Cloud SQL Admin client talks to handler
+func newTestSQL(t *testing.T, handler http.HandlerFunc) *Gcp {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	service, err := sqladmin.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("sqladmin.NewService() error = %v", err)
+	}
+	return &Gcp{ProjectID: "test", SqlService: service}
+}
+
+func TestExportSQLDatabase(t *testing.T) {
+	var exported sqladmin.InstancesExportRequest
+	gcp := newTestSQL(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &exported)
+		w.Write([]byte(`{"name":"op-1"}`))
+	})
+
+	if _, err := gcp.ExportSQLDatabase("db", "gs://backups/db.csv", SQLExportOptions{FileType: "CSV"}); err == nil {
+		t.Error("ExportSQLDatabase() expected error for a CSV export without a query")
+	}
+	if _, err := gcp.ExportSQLDatabase("db", "gs://backups/db.sql", SQLExportOptions{Databases: []string{"app"}}); err != nil {
+		t.Fatalf("ExportSQLDatabase() error = %v", err)
+	}
+	if export := exported.ExportContext; export.FileType != "SQL" || export.Uri != "gs://backups/db.sql" || export.CsvExportOptions != nil {
+		t.Errorf("export = %+v, want a SQL export", export)
+	}
+	if _, err := gcp.ExportSQLDatabase("db", "gs://backups/db.csv", SQLExportOptions{FileType: "CSV", Query: "SELECT 1"}); err != nil {
+		t.Fatalf("ExportSQLDatabase() error = %v", err)
+	}
+	if csv := exported.ExportContext.CsvExportOptions; csv == nil || csv.SelectQuery != "SELECT 1" {
+		t.Errorf("csv options = %+v, want the query", csv)
+	}
+}
+
+func TestImportSQLDatabase(t *testing.T) {
+	var imported sqladmin.InstancesImportRequest
+	gcp := newTestSQL(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &imported)
+		w.Write([]byte(`{"name":"op-1"}`))
+	})
+
+	if _, err := gcp.ImportSQLDatabase("db", "app", "gs://backups/users.csv", SQLImportOptions{FileType: "CSV"}); err == nil {
+		t.Error("ImportSQLDatabase() expected error for a CSV import without a table")
+	}
+	if _, err := gcp.ImportSQLDatabase("db", "app", "gs://backups/db.sql", SQLImportOptions{User: "admin"}); err != nil {
+		t.Fatalf("ImportSQLDatabase() error = %v", err)
+	}
+	if imp := imported.ImportContext; imp.FileType != "SQL" || imp.Database != "app" || imp.ImportUser != "admin" || imp.CsvImportOptions != nil {
+		t.Errorf("import = %+v, want a SQL import", imp)
+	}
+	opts := SQLImportOptions{FileType: "CSV", Table: "users", Columns: []string{"id", "name"}}
+	if _, err := gcp.ImportSQLDatabase("db", "app", "gs://backups/users.csv", opts); err != nil {
+		t.Fatalf("ImportSQLDatabase() error = %v", err)
+	}
+	if csv := imported.ImportContext.CsvImportOptions; csv == nil || csv.Table != "users" || len(csv.Columns) != 2 {
+		t.Errorf("csv options = %+v, want the table and columns", csv)
+	}
+}
+
+func TestSQLOperationError(t *testing.T) {
+	if err := sqlOperationError(&sqladmin.Operation{Name: "op-1"}); err != nil {
+		t.Errorf("sqlOperationError() = %v, want nil without errors", err)
+	}
+	if err := sqlOperationError(&sqladmin.Operation{Name: "op-1", Error: &sqladmin.OperationErrors{}}); err != nil {
+		t.Errorf("sqlOperationError() = %v, want nil for an empty error list", err)
+	}
+
+	err := sqlOperationError(&sqladmin.Operation{
+		Name:          "op-1",
+		OperationType: "EXPORT",
+		Error: &sqladmin.OperationErrors{Errors: []*sqladmin.OperationError{
+			{Code: "ERROR_RDBMS", Message: "table missing"},
+			{Code: "INTERNAL_ERROR", Message: "retry later"},
+		}},
+	})
+	want := "gcp: SQL operation EXPORT op-1 failed: ERROR_RDBMS: table missing\nINTERNAL_ERROR: retry later"
+	if err == nil || err.Error() != want {
+		t.Errorf("sqlOperationError() = %v, want %q", err, want)
+	}
+}
+
+func TestWaitForSQLOperation(t *testing.T) {
+	t.Run("done with errors", func(t *testing.T) {
+		gcp := newTestSQL(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"op-1","status":"DONE","error":{"errors":[{"code":"ERROR_RDBMS","message":"table missing"}]}}`))
+		})
+		op, err := gcp.WaitForSQLOperation(context.Background(), &sqladmin.Operation{Name: "op-1"}, 0)
+		if err == nil || !strings.Contains(err.Error(), "table missing") {
+			t.Errorf("WaitForSQLOperation() error = %v, want the operation error", err)
+		}
+		if op.Status != "DONE" {
+			t.Errorf("WaitForSQLOperation() status = %s, want DONE", op.Status)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		gcp := newTestSQL(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"op-1","status":"RUNNING"}`))
+		})
+		started := time.Now()
+		op, err := gcp.WaitForSQLOperation(context.Background(), &sqladmin.Operation{Name: "op-1"}, 50*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "did not finish in time") {
+			t.Errorf("WaitForSQLOperation() error = %v, want a timeout", err)
+		}
+		if op.Status != "RUNNING" {
+			t.Errorf("WaitForSQLOperation() status = %s, want the last seen RUNNING", op.Status)
+		}
+		if elapsed := time.Since(started); elapsed > sqlOperationPollInterval {
+			t.Errorf("WaitForSQLOperation() took %s, want it to stop at the timeout", elapsed)
+		}
+	})
+}
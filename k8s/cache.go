@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// CacheOptions configures the informer cache started by EnableCache
+type CacheOptions struct {
+	Namespace    string        // Only cache this namespace, empty caches the whole cluster
+	ResyncPeriod time.Duration // Periodic full resync, 0 disables it
+	SyncTimeout  time.Duration // How long to wait for the initial list, defaults to one minute
+}
+
+// kubeCache holds the listers backing cached reads
+type kubeCache struct {
+	namespace   string
+	deployments appslisters.DeploymentLister
+	pods        corelisters.PodLister
+	jobs        batchlisters.JobLister
+	configMaps  corelisters.ConfigMapLister
+}
+
+// EnableCache starts shared informers for Deployments, Pods, Jobs and ConfigMaps and waits for them to sync.
+// Until ctx is cancelled, Get and List methods for those kinds are served from the cache; writes always go to the API server.
+func (k *KubeClient) EnableCache(ctx context.Context, opts CacheOptions) error {
+	if opts.SyncTimeout <= 0 {
+		opts.SyncTimeout = time.Minute
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(k.Clientset, opts.ResyncPeriod, informers.WithNamespace(opts.Namespace))
+	c := &kubeCache{
+		namespace:   opts.Namespace,
+		deployments: factory.Apps().V1().Deployments().Lister(),
+		pods:        factory.Core().V1().Pods().Lister(),
+		jobs:        factory.Batch().V1().Jobs().Lister(),
+		configMaps:  factory.Core().V1().ConfigMaps().Lister(),
+	}
+	cacheCtx, stop := context.WithCancel(ctx)
+	factory.Start(cacheCtx.Done())
+
+	syncCtx, cancel := context.WithTimeout(cacheCtx, opts.SyncTimeout)
+	defer cancel()
+	for informerType, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			stop()
+			factory.Shutdown()
+			return fmt.Errorf("kube: cache for %v did not sync within %s", informerType, opts.SyncTimeout)
+		}
+	}
+
+	k.cache.Store(c)
+	go func() {
+		defer stop()
+		<-cacheCtx.Done()
+		k.cache.CompareAndSwap(c, nil)
+		factory.Shutdown()
+	}()
+	slog.Info("kube: cache synced", slog.String("Namespace", opts.Namespace))
+	return nil
+}
+
+// cacheFor returns the cache when it is enabled and covers the namespace, nil otherwise
+func (k *KubeClient) cacheFor(namespace string) *kubeCache {
+	c := k.cache.Load()
+	if c == nil || (c.namespace != "" && c.namespace != namespace) {
+		return nil
+	}
+	return c
+}
+
+// GetDeployment returns a deployment, from the cache when enabled
+func (k *KubeClient) GetDeployment(ctx context.Context, name, namespace string) (*appsv1.Deployment, error) {
+	var deployment *appsv1.Deployment
+	var err error
+	if c := k.cacheFor(namespace); c != nil {
+		deployment, err = c.deployments.Deployments(namespace).Get(name)
+		if err == nil {
+			deployment = deployment.DeepCopy()
+		}
+	} else {
+		deployment, err = k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve deployment %s: %w", name, err)
+	}
+	return deployment, nil
+}
+
+// GetPod returns a pod, from the cache when enabled
+func (k *KubeClient) GetPod(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	var err error
+	if c := k.cacheFor(namespace); c != nil {
+		pod, err = c.pods.Pods(namespace).Get(name)
+		if err == nil {
+			pod = pod.DeepCopy()
+		}
+	} else {
+		pod, err = k.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve pod %s: %w", name, err)
+	}
+	return pod, nil
+}
+
+// GetJob returns a job, from the cache when enabled
+func (k *KubeClient) GetJob(ctx context.Context, name, namespace string) (*batchv1.Job, error) {
+	var job *batchv1.Job
+	var err error
+	if c := k.cacheFor(namespace); c != nil {
+		job, err = c.jobs.Jobs(namespace).Get(name)
+		if err == nil {
+			job = job.DeepCopy()
+		}
+	} else {
+		job, err = k.Clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve job %s: %w", name, err)
+	}
+	return job, nil
+}
+
+// GetConfigMap returns a configmap, from the cache when enabled
+func (k *KubeClient) GetConfigMap(ctx context.Context, name, namespace string) (*corev1.ConfigMap, error) {
+	var configMap *corev1.ConfigMap
+	var err error
+	if c := k.cacheFor(namespace); c != nil {
+		configMap, err = c.configMaps.ConfigMaps(namespace).Get(name)
+		if err == nil {
+			configMap = configMap.DeepCopy()
+		}
+	} else {
+		configMap, err = k.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve configmap %s: %w", name, err)
+	}
+	return configMap, nil
+}
+
+// cachedList reports whether a list request can be answered from the cache: listers only
+// understand label selectors and always return the full result in one page
+func (k *KubeClient) cachedList(namespace string, opts ListOptions) (*kubeCache, labels.Selector, error) {
+	if opts.FieldSelector != "" || opts.Continue != "" {
+		return nil, nil, nil
+	}
+	c := k.cacheFor(namespace)
+	if c == nil {
+		return nil, nil, nil
+	}
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("kube: invalid label selector %q: %w", opts.LabelSelector, err)
+	}
+	return c, selector, nil
+}
+
+// deepCopier is satisfied by pointers to generated API types
+type deepCopier[T any] interface {
+	*T
+	DeepCopy() *T
+}
+
+// copyItems deep copies lister results so callers can't mutate the shared cache
+func copyItems[T any, P deepCopier[T]](items []P) []T {
+	values := make([]T, 0, len(items))
+	for _, item := range items {
+		values = append(values, *item.DeepCopy())
+	}
+	return values
+}
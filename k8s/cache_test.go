@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestEnableCache(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "default", Labels: map[string]string{"app": "db"}}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"}, Status: batchv1.JobStatus{Succeeded: 1}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}},
+	)
+	kube := &KubeClient{Clientset: clientset}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := kube.EnableCache(ctx, CacheOptions{Namespace: "default", SyncTimeout: 5 * time.Second}); err != nil {
+		t.Fatalf("EnableCache() error = %v", err)
+	}
+
+	// Once synced, reads must not reach the API server
+	clientset.PrependReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("unexpected get of %s", action.GetResource().Resource)
+	})
+	clientset.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("unexpected list of %s", action.GetResource().Resource)
+	})
+
+	if _, err := kube.GetDeployment(ctx, "web", "default"); err != nil {
+		t.Errorf("GetDeployment() error = %v", err)
+	}
+	if _, err := kube.GetPod(ctx, "web-1", "default"); err != nil {
+		t.Errorf("GetPod() error = %v", err)
+	}
+	if _, err := kube.GetConfigMap(ctx, "settings", "default"); err != nil {
+		t.Errorf("GetConfigMap() error = %v", err)
+	}
+	if completed, err := kube.IsJobCompleted(ctx, "migrate", "default"); err != nil || !completed {
+		t.Errorf("IsJobCompleted() = %v, %v, want true", completed, err)
+	}
+	pods, _, err := kube.ListPods(ctx, "default", ListOptions{LabelSelector: "app=web"})
+	if err != nil || len(pods) != 1 || pods[0].Name != "web-1" {
+		t.Errorf("ListPods() = %v, %v, want only web-1", pods, err)
+	}
+	if _, err := kube.GetPod(ctx, "web-1", "other"); err == nil {
+		t.Error("GetPod() outside the cached namespace should reach the API server")
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for kube.cacheFor("default") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("cache still enabled after context cancellation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScaleDownAllDeploymentsWithCache(t *testing.T) {
+	replicas := int32(3)
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+	)
+	kube := &KubeClient{Clientset: clientset}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := kube.EnableCache(ctx, CacheOptions{Namespace: "default", SyncTimeout: 5 * time.Second}); err != nil {
+		t.Fatalf("EnableCache() error = %v", err)
+	}
+
+	// Cached objects may be stale, writing them back with Update would conflict
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewConflict(appsv1.Resource("deployments"), "web", fmt.Errorf("stale object"))
+	})
+	if err := kube.ScaleDownAllDeploymentsInNamespace(ctx, "default"); err != nil {
+		t.Fatalf("ScaleDownAllDeploymentsInNamespace() error = %v", err)
+	}
+	deployment, err := clientset.Tracker().Get(appsv1.SchemeGroupVersion.WithResource("deployments"), "default", "web")
+	if err != nil {
+		t.Fatalf("deployment not found: %v", err)
+	}
+	if got := *deployment.(*appsv1.Deployment).Spec.Replicas; got != 0 {
+		t.Errorf("replicas = %d, want 0", got)
+	}
+}
+
+func TestEnableCacheSyncTimeout(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("simulated list failure")
+	})
+	kube := &KubeClient{Clientset: clientset}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := kube.EnableCache(ctx, CacheOptions{SyncTimeout: 200 * time.Millisecond}); err == nil {
+		t.Error("EnableCache() expected sync timeout error")
+	}
+	if kube.cacheFor("default") != nil {
+		t.Error("cache enabled despite failed sync")
+	}
+}
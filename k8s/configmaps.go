@@ -0,0 +1,290 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+)
+
+// ConfigData describes the desired content of a ConfigMap or Secret
+type ConfigData struct {
+	Data              map[string]string
+	BinaryData        map[string][]byte
+	Labels            map[string]string
+	Immutable         bool
+	SecretType        corev1.SecretType // Secrets only, defaults to Opaque
+	RecreateImmutable bool              // Delete and recreate an immutable object whose content changed instead of failing
+}
+
+// ConfigMapEvent is a change to a watched ConfigMap, ConfigMap is the last known state for deletions
+type ConfigMapEvent struct {
+	Type      watch.EventType
+	ConfigMap *corev1.ConfigMap
+}
+
+// UpsertConfigMap creates the ConfigMap or replaces its content when it already exists
+func (k *KubeClient) UpsertConfigMap(ctx context.Context, name, namespace string, cfg ConfigData) (*corev1.ConfigMap, error) {
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    cfg.Labels,
+		},
+		Data:       cfg.Data,
+		BinaryData: cfg.BinaryData,
+	}
+	if cfg.Immutable {
+		desired.Immutable = &cfg.Immutable
+	}
+	client := k.Clientset.CoreV1().ConfigMaps(namespace)
+
+	var result *corev1.ConfigMap
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := client.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result, err = client.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if isImmutable(existing.Immutable) {
+			if configMapDataEqual(existing, desired) {
+				result = existing
+				return nil
+			}
+			if !cfg.RecreateImmutable {
+				return fmt.Errorf("configmap %s is immutable", name)
+			}
+			if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			result, err = client.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		existing.Labels = mergeLabels(existing.Labels, cfg.Labels)
+		existing.Data = desired.Data
+		existing.BinaryData = desired.BinaryData
+		existing.Immutable = desired.Immutable
+		result, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to upsert configmap %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// MergeConfigMapData sets and removes individual keys of an existing ConfigMap, leaving other keys untouched
+func (k *KubeClient) MergeConfigMapData(ctx context.Context, name, namespace string, set map[string]string, remove []string) (*corev1.ConfigMap, error) {
+	client := k.Clientset.CoreV1().ConfigMaps(namespace)
+	var result *corev1.ConfigMap
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if isImmutable(configMap.Immutable) {
+			return fmt.Errorf("configmap %s is immutable", name)
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		for key, value := range set {
+			configMap.Data[key] = value
+			// A key can't be in both maps
+			delete(configMap.BinaryData, key)
+		}
+		for _, key := range remove {
+			delete(configMap.Data, key)
+			delete(configMap.BinaryData, key)
+		}
+		result, err = client.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to merge configmap %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// WatchConfigMap streams changes to a single ConfigMap until ctx is cancelled, the channel is closed afterwards
+func (k *KubeClient) WatchConfigMap(ctx context.Context, name, namespace string) (<-chan ConfigMapEvent, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(k.Clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+
+	events := make(chan ConfigMapEvent)
+	send := func(eventType watch.EventType, obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		configMap, ok := obj.(*corev1.ConfigMap)
+		if !ok || configMap.Name != name {
+			return
+		}
+		select {
+		case events <- ConfigMapEvent{Type: eventType, ConfigMap: configMap}:
+		case <-ctx.Done():
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { send(watch.Added, obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(*corev1.ConfigMap).ResourceVersion == newObj.(*corev1.ConfigMap).ResourceVersion {
+				return
+			}
+			send(watch.Modified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) { send(watch.Deleted, obj) },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to watch configmap %s: %w", name, err)
+	}
+
+	runInformer(ctx, informer, events)
+	return events, nil
+}
+
+// UpsertSecret creates the Secret or replaces its content when it already exists, Data and BinaryData are merged
+func (k *KubeClient) UpsertSecret(ctx context.Context, name, namespace string, cfg ConfigData) (*corev1.Secret, error) {
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    cfg.Labels,
+		},
+		Type: cfg.SecretType,
+		Data: secretData(cfg),
+	}
+	if desired.Type == "" {
+		desired.Type = corev1.SecretTypeOpaque
+	}
+	if cfg.Immutable {
+		desired.Immutable = &cfg.Immutable
+	}
+	client := k.Clientset.CoreV1().Secrets(namespace)
+
+	var result *corev1.Secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := client.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result, err = client.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		// The type of a Secret can't be changed in place either
+		if isImmutable(existing.Immutable) || existing.Type != desired.Type {
+			if maps.EqualFunc(existing.Data, desired.Data, bytes.Equal) && existing.Type == desired.Type {
+				result = existing
+				return nil
+			}
+			if !cfg.RecreateImmutable {
+				return fmt.Errorf("secret %s is immutable or changes type", name)
+			}
+			if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			result, err = client.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		existing.Labels = mergeLabels(existing.Labels, cfg.Labels)
+		existing.Data = desired.Data
+		existing.Immutable = desired.Immutable
+		result, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to upsert secret %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// MergeSecretData sets and removes individual keys of an existing Secret, leaving other keys untouched
+func (k *KubeClient) MergeSecretData(ctx context.Context, name, namespace string, set map[string][]byte, remove []string) (*corev1.Secret, error) {
+	client := k.Clientset.CoreV1().Secrets(namespace)
+	var result *corev1.Secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if isImmutable(secret.Immutable) {
+			return fmt.Errorf("secret %s is immutable", name)
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		for key, value := range set {
+			secret.Data[key] = value
+		}
+		for _, key := range remove {
+			delete(secret.Data, key)
+		}
+		result, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to merge secret %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// GetSecretData returns the decoded content of a Secret
+func (k *KubeClient) GetSecretData(ctx context.Context, name, namespace string) (map[string][]byte, error) {
+	secret, err := k.Clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve secret %s: %w", name, err)
+	}
+	return secret.Data, nil
+}
+
+func secretData(cfg ConfigData) map[string][]byte {
+	data := make(map[string][]byte, len(cfg.Data)+len(cfg.BinaryData))
+	for key, value := range cfg.Data {
+		data[key] = []byte(value)
+	}
+	for key, value := range cfg.BinaryData {
+		data[key] = value
+	}
+	return data
+}
+
+// configMapDataEqual compares by content, the API returns nil for maps that are sent empty
+func configMapDataEqual(a, b *corev1.ConfigMap) bool {
+	return maps.Equal(a.Data, b.Data) && maps.EqualFunc(a.BinaryData, b.BinaryData, bytes.Equal)
+}
+
+func isImmutable(immutable *bool) bool {
+	return immutable != nil && *immutable
+}
+
+// mergeLabels overlays desired labels on the existing ones so labels added by others survive an upsert
+func mergeLabels(existing, desired map[string]string) map[string]string {
+	if len(desired) == 0 {
+		return existing
+	}
+	if existing == nil {
+		existing = make(map[string]string, len(desired))
+	}
+	for key, value := range desired {
+		existing[key] = value
+	}
+	return existing
+}
@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUpsertConfigMap(t *testing.T) {
+	immutable := true
+	tests := []struct {
+		name     string
+		existing *corev1.ConfigMap
+		cfg      ConfigData
+		wantData string
+		wantErr  bool
+	}{
+		{
+			name:     "creates missing configmap",
+			cfg:      ConfigData{Data: map[string]string{"key": "value"}},
+			wantData: "value",
+		},
+		{
+			name: "updates existing configmap",
+			existing: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default"},
+				Data:       map[string]string{"key": "old"},
+			},
+			cfg:      ConfigData{Data: map[string]string{"key": "new"}},
+			wantData: "new",
+		},
+		{
+			name: "immutable configmap with changed data",
+			existing: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default"},
+				Data:       map[string]string{"key": "old"},
+				Immutable:  &immutable,
+			},
+			cfg:     ConfigData{Data: map[string]string{"key": "new"}},
+			wantErr: true,
+		},
+		{
+			name: "immutable configmap recreated",
+			existing: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default"},
+				Data:       map[string]string{"key": "old"},
+				Immutable:  &immutable,
+			},
+			cfg:      ConfigData{Data: map[string]string{"key": "new"}, Immutable: true, RecreateImmutable: true},
+			wantData: "new",
+		},
+		{
+			name: "immutable empty configmap unchanged",
+			existing: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default"},
+				Immutable:  &immutable,
+			},
+			cfg: ConfigData{Data: map[string]string{}, BinaryData: map[string][]byte{}, Immutable: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			if tt.existing != nil {
+				_, _ = clientset.CoreV1().ConfigMaps("default").Create(context.TODO(), tt.existing, metav1.CreateOptions{})
+			}
+			kube := &KubeClient{Clientset: clientset}
+			got, err := kube.UpsertConfigMap(context.TODO(), "test-configmap", "default", tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpsertConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Data["key"] != tt.wantData {
+				t.Errorf("UpsertConfigMap() data = %v, want %v", got.Data["key"], tt.wantData)
+			}
+		})
+	}
+}
+
+func TestMergeConfigMapData(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default"},
+		Data:       map[string]string{"keep": "1", "drop": "2"},
+		BinaryData: map[string][]byte{"blob": {0x1}, "replace": {0x2}},
+	})
+	kube := &KubeClient{Clientset: clientset}
+	got, err := kube.MergeConfigMapData(context.TODO(), "test-configmap", "default", map[string]string{"add": "3", "replace": "4"}, []string{"drop"})
+	if err != nil {
+		t.Fatalf("MergeConfigMapData() error = %v", err)
+	}
+	want := map[string]string{"keep": "1", "add": "3", "replace": "4"}
+	if len(got.Data) != len(want) || got.Data["keep"] != "1" || got.Data["add"] != "3" || got.Data["replace"] != "4" {
+		t.Errorf("MergeConfigMapData() data = %v, want %v", got.Data, want)
+	}
+	if _, ok := got.BinaryData["replace"]; ok || len(got.BinaryData) != 1 {
+		t.Errorf("MergeConfigMapData() binary data = %v, want only blob", got.BinaryData)
+	}
+}
+
+func TestUpsertAndMergeSecret(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	cfg := ConfigData{
+		Data:       map[string]string{"user": "admin"},
+		BinaryData: map[string][]byte{"cert": {0x1, 0x2}},
+	}
+	secret, err := kube.UpsertSecret(context.TODO(), "test-secret", "default", cfg)
+	if err != nil {
+		t.Fatalf("UpsertSecret() error = %v", err)
+	}
+	if secret.Type != corev1.SecretTypeOpaque || string(secret.Data["user"]) != "admin" || len(secret.Data["cert"]) != 2 {
+		t.Errorf("UpsertSecret() got %+v", secret)
+	}
+
+	_, err = kube.MergeSecretData(context.TODO(), "test-secret", "default", map[string][]byte{"password": []byte("s3cret")}, []string{"cert"})
+	if err != nil {
+		t.Fatalf("MergeSecretData() error = %v", err)
+	}
+	data, err := kube.GetSecretData(context.TODO(), "test-secret", "default")
+	if err != nil {
+		t.Fatalf("GetSecretData() error = %v", err)
+	}
+	if string(data["password"]) != "s3cret" || string(data["user"]) != "admin" || data["cert"] != nil {
+		t.Errorf("GetSecretData() got %v", data)
+	}
+}
+
+func TestUpsertImmutableEmptySecret(t *testing.T) {
+	immutable := true
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "default", UID: "original"},
+		Type:       corev1.SecretTypeOpaque,
+		Immutable:  &immutable,
+	})
+	kube := &KubeClient{Clientset: clientset}
+
+	if _, err := kube.UpsertSecret(context.TODO(), "test-secret", "default", ConfigData{Immutable: true}); err != nil {
+		t.Fatalf("UpsertSecret() error = %v for an unchanged empty secret", err)
+	}
+	secret, err := kube.UpsertSecret(context.TODO(), "test-secret", "default", ConfigData{Immutable: true, RecreateImmutable: true})
+	if err != nil {
+		t.Fatalf("UpsertSecret() error = %v", err)
+	}
+	if secret.UID != "original" {
+		t.Error("UpsertSecret() recreated an unchanged empty secret")
+	}
+}
+
+func TestWatchConfigMap(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default", ResourceVersion: "1"},
+		Data:       map[string]string{"key": "old"},
+	})
+	kube := &KubeClient{Clientset: clientset}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := kube.WatchConfigMap(ctx, "test-configmap", "default")
+	if err != nil {
+		t.Fatalf("WatchConfigMap() error = %v", err)
+	}
+	next := func() ConfigMapEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for configmap event")
+			return ConfigMapEvent{}
+		}
+	}
+	if event := next(); event.Type != watch.Added {
+		t.Errorf("first event = %v, want %v", event.Type, watch.Added)
+	}
+
+	_, err = clientset.CoreV1().ConfigMaps("default").Update(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default", ResourceVersion: "2"},
+		Data:       map[string]string{"key": "new"},
+	}, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if event := next(); event.Type != watch.Modified || event.ConfigMap.Data["key"] != "new" {
+		t.Errorf("second event = %v %v, want modified with new data", event.Type, event.ConfigMap.Data)
+	}
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,229 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ManualInstantiationAnnotation marks jobs created by hand from a CronJob, as kubectl create job --from does
+const ManualInstantiationAnnotation = "cronjob.kubernetes.io/instantiate"
+
+// JobOutcome is the coarse state of a Job
+type JobOutcome string
+
+const (
+	JobRunning   JobOutcome = "Running"
+	JobSucceeded JobOutcome = "Succeeded"
+	JobFailed    JobOutcome = "Failed"
+)
+
+// JobRun summarizes a Job spawned by a CronJob
+type JobRun struct {
+	Name           string     `json:"name"`
+	Outcome        JobOutcome `json:"outcome"`
+	Manual         bool       `json:"manual"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	Succeeded      int32      `json:"succeeded"`
+	Failed         int32      `json:"failed"`
+}
+
+// SuspendCronJob stops the CronJob from scheduling new runs
+func (k *KubeClient) SuspendCronJob(ctx context.Context, name, namespace string) error {
+	return k.setCronJobSuspend(ctx, name, namespace, true)
+}
+
+// ResumeCronJob lets a suspended CronJob schedule runs again
+func (k *KubeClient) ResumeCronJob(ctx context.Context, name, namespace string) error {
+	return k.setCronJobSuspend(ctx, name, namespace, false)
+}
+
+func (k *KubeClient) setCronJobSuspend(ctx context.Context, name, namespace string, suspend bool) error {
+	patchData := fmt.Sprintf(`{"spec": {"suspend": %t}}`, suspend)
+	_, err := k.Clientset.BatchV1().CronJobs(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patchData), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("kube: unable to set suspend=%t on cronjob %s: %w", suspend, name, err)
+	}
+	return nil
+}
+
+// SetCronJobSchedule changes the schedule of a CronJob, an empty timeZone keeps the current one
+func (k *KubeClient) SetCronJobSchedule(ctx context.Context, name, namespace, schedule, timeZone string) error {
+	if _, err := parseSchedule(schedule, timeZone); err != nil {
+		return err
+	}
+	cronjob, err := k.Clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("kube: unable to retrieve cronjob %s: %w", name, err)
+	}
+	cronjob.Spec.Schedule = schedule
+	if timeZone != "" {
+		cronjob.Spec.TimeZone = &timeZone
+	}
+	_, err = k.Clientset.BatchV1().CronJobs(namespace).Update(ctx, cronjob, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("kube: unable to update cronjob %s schedule: %w", name, err)
+	}
+	return nil
+}
+
+// CronJobRuns lists the Jobs owned by a CronJob, newest first, limit <= 0 returns all of them
+func (k *KubeClient) CronJobRuns(ctx context.Context, name, namespace string, limit int) ([]JobRun, error) {
+	cronjob, err := k.Clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve cronjob %s: %w", name, err)
+	}
+	jobs, _, err := k.ListJobs(ctx, namespace, ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []JobRun
+	for _, job := range jobs {
+		if !isOwnedBy(job.OwnerReferences, cronjob.UID) {
+			continue
+		}
+		runs = append(runs, jobRun(&job))
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// NextCronJobRuns computes the next n times the CronJob will be scheduled, empty if it is suspended
+func (k *KubeClient) NextCronJobRuns(ctx context.Context, name, namespace string, n int) ([]time.Time, error) {
+	cronjob, err := k.Clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve cronjob %s: %w", name, err)
+	}
+	if cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend {
+		return nil, nil
+	}
+	timeZone := ""
+	if cronjob.Spec.TimeZone != nil {
+		timeZone = *cronjob.Spec.TimeZone
+	}
+	return NextRunTimes(cronjob.Spec.Schedule, timeZone, time.Now(), n)
+}
+
+// NextRunTimes returns the next n activations of a cron expression after from, an empty timeZone means UTC
+func NextRunTimes(schedule, timeZone string, from time.Time, n int) ([]time.Time, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("kube: invalid number of run times %d", n)
+	}
+	sched, err := parseSchedule(schedule, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, n)
+	next := from
+	for i := 0; i < n; i++ {
+		next = sched.Next(next)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+	}
+	return times, nil
+}
+
+func parseSchedule(schedule, timeZone string) (cron.Schedule, error) {
+	location := time.UTC
+	if timeZone != "" {
+		var err error
+		location, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("kube: invalid time zone %q: %w", timeZone, err)
+		}
+	}
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("kube: invalid cron schedule %q: %w", schedule, err)
+	}
+	if specSchedule, ok := sched.(*cron.SpecSchedule); ok {
+		specSchedule.Location = location
+	}
+	return sched, nil
+}
+
+// jobFromCronJob builds a Job from the CronJob template, mirroring kubectl create job --from
+func jobFromCronJob(cronjob *batchv1.CronJob, jobName string) *batchv1.Job {
+	annotations := map[string]string{ManualInstantiationAnnotation: "manual"}
+	for key, value := range cronjob.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   cronjob.Namespace,
+			Labels:      cronjob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronjob, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cronjob.Spec.JobTemplate.Spec,
+	}
+}
+
+func jobRun(job *batchv1.Job) JobRun {
+	run := JobRun{
+		Name:      job.Name,
+		Outcome:   jobOutcome(job),
+		Manual:    job.Annotations[ManualInstantiationAnnotation] == "manual",
+		CreatedAt: job.CreationTimestamp.Time,
+		Succeeded: job.Status.Succeeded,
+		Failed:    job.Status.Failed,
+	}
+	if job.Status.StartTime != nil {
+		run.StartTime = &job.Status.StartTime.Time
+	}
+	if job.Status.CompletionTime != nil {
+		run.CompletionTime = &job.Status.CompletionTime.Time
+	}
+	return run
+}
+
+// jobOutcome prefers the terminal Job conditions and falls back to pod counters
+func jobOutcome(job *batchv1.Job) JobOutcome {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return JobSucceeded
+		case batchv1.JobFailed:
+			return JobFailed
+		}
+	}
+	if job.Status.Succeeded > 0 {
+		return JobSucceeded
+	}
+	if job.Status.Failed > 0 && job.Status.Active == 0 {
+		return JobFailed
+	}
+	return JobRunning
+}
+
+func isOwnedBy(refs []metav1.OwnerReference, uid types.UID) bool {
+	for _, ref := range refs {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
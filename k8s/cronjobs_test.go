@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestCronJob() *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cronjob",
+			Namespace: "default",
+			UID:       "cronjob-uid",
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 3 * * *",
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "backup"}},
+			},
+		},
+	}
+}
+
+func TestTriggerJobFromCronJobMetadata(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestCronJob())
+	kube := &KubeClient{Clientset: clientset}
+	jobName, err := kube.TriggerJobFromCronJob(context.TODO(), "test-cronjob", "default")
+	if err != nil {
+		t.Fatalf("TriggerJobFromCronJob() error = %v", err)
+	}
+	job, err := clientset.BatchV1().Jobs("default").Get(context.TODO(), jobName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if job.Annotations[ManualInstantiationAnnotation] != "manual" {
+		t.Errorf("job annotations = %v, want %s=manual", job.Annotations, ManualInstantiationAnnotation)
+	}
+	if len(job.OwnerReferences) != 1 || job.OwnerReferences[0].UID != "cronjob-uid" || job.OwnerReferences[0].Kind != "CronJob" {
+		t.Errorf("job owner references = %v, want the cronjob", job.OwnerReferences)
+	}
+	if job.Labels["app"] != "backup" {
+		t.Errorf("job labels = %v, want template labels", job.Labels)
+	}
+}
+
+func TestSuspendResumeCronJob(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestCronJob())
+	kube := &KubeClient{Clientset: clientset}
+
+	if err := kube.SuspendCronJob(context.TODO(), "test-cronjob", "default"); err != nil {
+		t.Fatalf("SuspendCronJob() error = %v", err)
+	}
+	cronjob, _ := clientset.BatchV1().CronJobs("default").Get(context.TODO(), "test-cronjob", metav1.GetOptions{})
+	if cronjob.Spec.Suspend == nil || !*cronjob.Spec.Suspend {
+		t.Errorf("SuspendCronJob() suspend = %v, want true", cronjob.Spec.Suspend)
+	}
+	runs, err := kube.NextCronJobRuns(context.TODO(), "test-cronjob", "default", 3)
+	if err != nil || len(runs) != 0 {
+		t.Errorf("NextCronJobRuns() on suspended cronjob = %v, %v, want none", runs, err)
+	}
+
+	if err := kube.ResumeCronJob(context.TODO(), "test-cronjob", "default"); err != nil {
+		t.Fatalf("ResumeCronJob() error = %v", err)
+	}
+	cronjob, _ = clientset.BatchV1().CronJobs("default").Get(context.TODO(), "test-cronjob", metav1.GetOptions{})
+	if cronjob.Spec.Suspend == nil || *cronjob.Spec.Suspend {
+		t.Errorf("ResumeCronJob() suspend = %v, want false", cronjob.Spec.Suspend)
+	}
+}
+
+func TestSetCronJobSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		timeZone string
+		wantErr  bool
+	}{
+		{name: "valid schedule", schedule: "*/5 * * * *", timeZone: "Europe/Berlin"},
+		{name: "descriptor schedule", schedule: "@hourly"},
+		{name: "invalid schedule", schedule: "every minute", wantErr: true},
+		{name: "invalid time zone", schedule: "* * * * *", timeZone: "Mars/Olympus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(newTestCronJob())
+			kube := &KubeClient{Clientset: clientset}
+			err := kube.SetCronJobSchedule(context.TODO(), "test-cronjob", "default", tt.schedule, tt.timeZone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetCronJobSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			cronjob, _ := clientset.BatchV1().CronJobs("default").Get(context.TODO(), "test-cronjob", metav1.GetOptions{})
+			if cronjob.Spec.Schedule != tt.schedule {
+				t.Errorf("SetCronJobSchedule() schedule = %v, want %v", cronjob.Spec.Schedule, tt.schedule)
+			}
+		})
+	}
+}
+
+func TestCronJobRuns(t *testing.T) {
+	cronjob := newTestCronJob()
+	owner := []metav1.OwnerReference{{Kind: "CronJob", Name: cronjob.Name, UID: cronjob.UID}}
+	now := time.Now()
+	clientset := fake.NewSimpleClientset(cronjob,
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: "default", OwnerReferences: owner, CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))},
+			Status:     batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: "True"}}},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default", OwnerReferences: owner, CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+			Status:     batchv1.JobStatus{Succeeded: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"},
+		},
+	)
+	kube := &KubeClient{Clientset: clientset}
+
+	runs, err := kube.CronJobRuns(context.TODO(), "test-cronjob", "default", 0)
+	if err != nil {
+		t.Fatalf("CronJobRuns() error = %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("CronJobRuns() got %d runs, want 2", len(runs))
+	}
+	if runs[0].Name != "new" || runs[0].Outcome != JobSucceeded || runs[1].Outcome != JobFailed {
+		t.Errorf("CronJobRuns() = %+v, want new succeeded then old failed", runs)
+	}
+
+	runs, _ = kube.CronJobRuns(context.TODO(), "test-cronjob", "default", 1)
+	if len(runs) != 1 {
+		t.Errorf("CronJobRuns() with limit got %d runs, want 1", len(runs))
+	}
+}
+
+func TestNextRunTimes(t *testing.T) {
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		timeZone string
+		n        int
+		want     []time.Time
+		wantErr  bool
+	}{
+		{name: "time zone", timeZone: "America/New_York", n: 2, want: []time.Time{first, first.Add(24 * time.Hour)}},
+		{name: "utc", n: 1, want: []time.Time{time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)}},
+		{name: "zero runs", n: 0, want: []time.Time{}},
+		{name: "negative runs", n: -1, wantErr: true},
+		{name: "invalid time zone", timeZone: "Mars/Olympus", n: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times, err := NextRunTimes("0 3 * * *", tt.timeZone, from, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextRunTimes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.EqualFunc(times, tt.want, time.Time.Equal) {
+				t.Errorf("NextRunTimes() = %v, want %v", times, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package k8s
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Logs larger than this are truncated in the diagnostics bundle
+const diagnosticsLogLimit = 10 << 20
+
+// DebugOptions configures the ephemeral container started by AttachDebugContainer
+type DebugOptions struct {
+	Name    string        // Container name, defaults to debugger-<random>
+	Target  string        // Container whose process namespace is shared, defaults to the first container
+	Command []string      // Defaults to the image entrypoint
+	Timeout time.Duration // How long to wait for the container to start, 0 returns right after it is added
+}
+
+// AttachDebugContainer adds an ephemeral container to a running pod, like kubectl debug, and returns its name.
+// The container keeps stdin and a TTY open so it can be attached to.
+func (k *KubeClient) AttachDebugContainer(ctx context.Context, name, namespace, image string, opts DebugOptions) (string, error) {
+	pod, err := k.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("kube: unable to retrieve pod %s: %w", name, err)
+	}
+	if opts.Name == "" {
+		opts.Name = "debugger-" + utilrand.String(5)
+	}
+	if opts.Target == "" && len(pod.Spec.Containers) > 0 {
+		opts.Target = pod.Spec.Containers[0].Name
+	}
+
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     opts.Name,
+			Image:                    image,
+			Command:                  opts.Command,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+		},
+		TargetContainerName: opts.Target,
+	})
+	_, err = k.Clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, name, pod, metav1.UpdateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("kube: unable to add debug container to pod %s: %w", name, err)
+	}
+	slog.Info("kube: debug container added", slog.String("Pod", name), slog.String("Container", opts.Name), slog.String("Image", image))
+
+	if opts.Timeout <= 0 {
+		return opts.Name, nil
+	}
+	err = wait.PollUntilContextTimeout(ctx, time.Second, opts.Timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := k.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range current.Status.EphemeralContainerStatuses {
+			if status.Name != opts.Name {
+				continue
+			}
+			if status.State.Terminated != nil {
+				return false, fmt.Errorf("exited with %s", status.State.Terminated.Reason)
+			}
+			return status.State.Running != nil, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return opts.Name, fmt.Errorf("kube: debug container %s did not start: %w", opts.Name, err)
+	}
+	return opts.Name, nil
+}
+
+// CollectDiagnostics writes a tar.gz bundle for a pod to w: the pod spec and status, its events, and the current
+// and previous logs of every container. Logs that can't be read are noted in errors.txt instead of failing the bundle.
+func (k *KubeClient) CollectDiagnostics(ctx context.Context, name, namespace string, w io.Writer) error {
+	pod, err := k.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("kube: unable to retrieve pod %s: %w", name, err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	prefix := namespace + "-" + name + "/"
+	var problems []string
+
+	pod.ManagedFields = nil
+	pod.APIVersion, pod.Kind = "v1", "Pod"
+	podJSON, err := json.MarshalIndent(pod, "", "  ")
+	if err != nil {
+		return fmt.Errorf("kube: unable to encode pod %s: %w", name, err)
+	}
+	if err := addTarFile(tw, prefix+"pod.json", podJSON); err != nil {
+		return err
+	}
+
+	events, err := k.podEvents(ctx, pod)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := addTarFile(tw, prefix+"events.txt", []byte(formatPodEvents(events))); err != nil {
+		return err
+	}
+
+	for _, c := range podContainerStatuses(pod) {
+		logs, err := k.containerLogs(ctx, pod, c.Name, false)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("logs of %s: %v", c.Name, err))
+		} else if err := addTarFile(tw, prefix+"logs/"+c.Name+".log", logs); err != nil {
+			return err
+		}
+		if c.RestartCount == 0 && c.LastTerminationState.Terminated == nil {
+			continue
+		}
+		logs, err = k.containerLogs(ctx, pod, c.Name, true)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("previous logs of %s: %v", c.Name, err))
+		} else if err := addTarFile(tw, prefix+"logs/"+c.Name+".previous.log", logs); err != nil {
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		if err := addTarFile(tw, prefix+"errors.txt", []byte(strings.Join(problems, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("kube: unable to write diagnostics bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("kube: unable to write diagnostics bundle: %w", err)
+	}
+	return nil
+}
+
+// SaveDiagnostics writes the CollectDiagnostics bundle to a file in dir and returns its path, ready to be
+// uploaded with the gcp storage helpers. An empty dir uses the system temp directory.
+func (k *KubeClient) SaveDiagnostics(ctx context.Context, name, namespace, dir string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%s.tar.gz", namespace, name, time.Now().Format("20060102-150405")))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("kube: unable to create %s: %w", path, err)
+	}
+	err = k.CollectDiagnostics(ctx, name, namespace, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("kube: unable to write %s: %w", path, closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	slog.Info("kube: diagnostics collected", slog.String("Pod", name), slog.String("Path", path))
+	return path, nil
+}
+
+// podEvents returns the events about the pod, oldest first
+func (k *KubeClient) podEvents(ctx context.Context, pod *corev1.Pod) ([]ClusterEvent, error) {
+	list, err := k.Clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + pod.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("events: %v", err)
+	}
+	var events []ClusterEvent
+	for i := range list.Items {
+		event := &list.Items[i]
+		// Filter again, the selector may be ignored and an earlier pod may have had the same name
+		if event.InvolvedObject.Kind == "Pod" && event.InvolvedObject.Name == pod.Name &&
+			(event.InvolvedObject.UID == "" || event.InvolvedObject.UID == pod.UID) {
+			events = append(events, toClusterEvent(event))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].LastSeen.Before(events[j].LastSeen) })
+	return events, nil
+}
+
+func formatPodEvents(events []ClusterEvent) string {
+	if len(events) == 0 {
+		return "No events\n"
+	}
+	var b strings.Builder
+	for _, event := range events {
+		fmt.Fprintf(&b, "%s x%d %s\n", event.LastSeen.Format(time.RFC3339), max(event.Count, 1), event)
+	}
+	return b.String()
+}
+
+// podContainerStatuses lists init and regular containers, with their status when the kubelet reported one
+func podContainerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	reported := map[string]corev1.ContainerStatus{}
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		reported[status.Name] = status
+	}
+	var statuses []corev1.ContainerStatus
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		status, ok := reported[c.Name]
+		if !ok {
+			status = corev1.ContainerStatus{Name: c.Name}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (k *KubeClient) containerLogs(ctx context.Context, pod *corev1.Pod, container string, previous bool) ([]byte, error) {
+	limit := int64(diagnosticsLogLimit)
+	stream, err := k.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		LimitBytes: &limit,
+	}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return io.ReadAll(io.LimitReader(stream, limit))
+}
+
+func addTarFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("kube: unable to write diagnostics bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("kube: unable to write diagnostics bundle: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newCrashingPod() *corev1.Pod {
+	pod := newTestPod("web-1", false)
+	pod.UID = "pod-uid"
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{Name: "init"}}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "postgres", RestartCount: 3}}
+	return pod
+}
+
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("bundle is not gzipped: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("bundle is not a tar archive: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		files[header.Name] = string(content)
+	}
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	event := newTestEvent("web-1.backoff", "Pod", "BackOff", corev1.EventTypeWarning)
+	event.Message = "Back-off restarting failed container"
+	other := newTestEvent("db-1.pulled", "Pod", "Pulled", corev1.EventTypeNormal)
+	other.InvolvedObject.Name = "db-1"
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset(newCrashingPod(), event, other)}
+
+	var buf bytes.Buffer
+	if err := kube.CollectDiagnostics(context.TODO(), "web-1", "default", &buf); err != nil {
+		t.Fatalf("CollectDiagnostics() error = %v", err)
+	}
+	files := readBundle(t, buf.Bytes())
+
+	for _, name := range []string{"pod.json", "events.txt", "logs/init.log", "logs/postgres.log", "logs/postgres.previous.log"} {
+		if _, ok := files["default-web-1/"+name]; !ok {
+			t.Errorf("bundle is missing %s, has %v", name, files)
+		}
+	}
+	if _, ok := files["default-web-1/logs/init.previous.log"]; ok {
+		t.Error("bundle has previous logs for a container that never restarted")
+	}
+	events := files["default-web-1/events.txt"]
+	if !strings.Contains(events, "Back-off restarting failed container") || strings.Contains(events, "Pulled") {
+		t.Errorf("events.txt = %q, want only the pod's events", events)
+	}
+	if !strings.Contains(files["default-web-1/pod.json"], `"name": "web-1"`) {
+		t.Errorf("pod.json = %s", files["default-web-1/pod.json"])
+	}
+}
+
+func TestSaveDiagnostics(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset(newCrashingPod())}
+
+	path, err := kube.SaveDiagnostics(context.TODO(), "web-1", "default", t.TempDir())
+	if err != nil {
+		t.Fatalf("SaveDiagnostics() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("bundle not written: %v", err)
+	}
+	if files := readBundle(t, data); len(files) == 0 {
+		t.Error("bundle is empty")
+	}
+
+	if _, err := kube.SaveDiagnostics(context.TODO(), "missing", "default", t.TempDir()); err == nil {
+		t.Error("SaveDiagnostics() expected error for a missing pod")
+	}
+}
+
+func TestAttachDebugContainer(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newCrashingPod())
+	kube := &KubeClient{Clientset: clientset}
+
+	name, err := kube.AttachDebugContainer(context.TODO(), "web-1", "default", "busybox", DebugOptions{Command: []string{"sh"}})
+	if err != nil {
+		t.Fatalf("AttachDebugContainer() error = %v", err)
+	}
+	if !strings.HasPrefix(name, "debugger-") {
+		t.Errorf("AttachDebugContainer() = %s, want a generated debugger name", name)
+	}
+
+	pod, _ := clientset.CoreV1().Pods("default").Get(context.TODO(), "web-1", metav1.GetOptions{})
+	if len(pod.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("pod has %d ephemeral containers, want 1", len(pod.Spec.EphemeralContainers))
+	}
+	debug := pod.Spec.EphemeralContainers[0]
+	if debug.Name != name || debug.Image != "busybox" || debug.TargetContainerName != "postgres" || !debug.Stdin || !debug.TTY {
+		t.Errorf("ephemeral container = %+v", debug)
+	}
+}
@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/itsvictorfy/pkg/email"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultDedupWindow is how long repeats of the same event are suppressed when the filter sets no window
+const DefaultDedupWindow = 5 * time.Minute
+
+// EventFilter selects which events WatchEvents delivers, empty fields match everything
+type EventFilter struct {
+	Namespace       string        // Watch a single namespace instead of the whole cluster
+	Kinds           []string      // Involved object kinds, e.g. Pod or Node
+	Reasons         []string      // Event reasons, e.g. OOMKilling, FailedScheduling or BackOff
+	IncludeNormal   bool          // Deliver Normal events as well as Warnings
+	IncludeExisting bool          // Deliver events last seen before the watch started
+	DedupWindow     time.Duration // Suppress repeats of the same object and reason, defaults to DefaultDedupWindow
+}
+
+// ClusterEvent is a flattened Kubernetes event
+type ClusterEvent struct {
+	Type      string    `json:"type"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// EventHandler is called for every event delivered by WatchEvents, before it is sent on the channel
+type EventHandler func(ClusterEvent)
+
+func (e ClusterEvent) String() string {
+	return fmt.Sprintf("%s %s %s/%s/%s: %s", e.Type, e.Reason, e.Namespace, e.Kind, e.Name, e.Message)
+}
+
+// WatchEvents streams matching events until ctx is cancelled, the channel is closed afterwards.
+// Events are dropped with a log line if the channel buffer is full, handlers always run.
+func (k *KubeClient) WatchEvents(ctx context.Context, filter EventFilter, handlers ...EventHandler) (<-chan ClusterEvent, error) {
+	options := []informers.SharedInformerOption{informers.WithNamespace(filter.Namespace)}
+	if !filter.IncludeNormal {
+		options = append(options, informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("type", corev1.EventTypeWarning).String()
+		}))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(k.Clientset, 0, options...)
+	informer := factory.Core().V1().Events().Informer()
+
+	dedup := newEventDeduper(filter.DedupWindow)
+	started := time.Now()
+	events := make(chan ClusterEvent, 100)
+	deliver := func(obj interface{}) {
+		event, ok := obj.(*corev1.Event)
+		if !ok {
+			return
+		}
+		clusterEvent := toClusterEvent(event)
+		if !filter.matches(clusterEvent) {
+			return
+		}
+		if !filter.IncludeExisting && clusterEvent.LastSeen.Before(started) {
+			return
+		}
+		if !dedup.allow(clusterEvent, time.Now()) {
+			return
+		}
+		for _, handler := range handlers {
+			handler(clusterEvent)
+		}
+		select {
+		case events <- clusterEvent:
+		default:
+			slog.Warn("kube: event channel full, dropping event", slog.String("Event", clusterEvent.String()))
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    deliver,
+		UpdateFunc: func(_, newObj interface{}) { deliver(newObj) },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to watch events: %w", err)
+	}
+
+	runInformer(ctx, informer, events)
+	return events, nil
+}
+
+// maxPendingEmails bounds the event emails EmailEventHandler sends at once, further events are dropped
+const maxPendingEmails = 10
+
+// EmailEventHandler returns a handler that mails every delivered event using the given configuration.
+// Mails are sent in the background so a slow mail server doesn't hold up the watch, and events are
+// dropped with a log line while maxPendingEmails mails are still being sent.
+func EmailEventHandler(conf *email.EmailConfig) EventHandler {
+	return asyncEventHandler(maxPendingEmails, func(event ClusterEvent) {
+		message := fmt.Sprintf("Subject: [kube] %s %s %s/%s\r\n\r\n%s\r\nCount: %d\r\nLast seen: %s\r\n",
+			event.Reason, event.Kind, event.Namespace, event.Name, event.Message, event.Count, event.LastSeen.Format(time.RFC3339))
+		if err := conf.SendEmail([]byte(message)); err != nil {
+			slog.Error("kube: unable to send event email", slog.String("Event", event.String()), slog.String("Error", err.Error()))
+		}
+	})
+}
+
+// asyncEventHandler runs handler in its own goroutine, at most limit at a time
+func asyncEventHandler(limit int, handler EventHandler) EventHandler {
+	pending := make(chan struct{}, limit)
+	return func(event ClusterEvent) {
+		select {
+		case pending <- struct{}{}:
+		default:
+			slog.Warn("kube: too many pending event handlers, dropping event", slog.String("Event", event.String()))
+			return
+		}
+		go func() {
+			defer func() { <-pending }()
+			handler(event)
+		}()
+	}
+}
+
+func (f EventFilter) matches(event ClusterEvent) bool {
+	if !f.IncludeNormal && event.Type != corev1.EventTypeWarning {
+		return false
+	}
+	if f.Namespace != "" && event.Namespace != f.Namespace {
+		return false
+	}
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, event.Kind) {
+		return false
+	}
+	if len(f.Reasons) > 0 && !slices.Contains(f.Reasons, event.Reason) {
+		return false
+	}
+	return true
+}
+
+func toClusterEvent(event *corev1.Event) ClusterEvent {
+	lastSeen := event.LastTimestamp.Time
+	if event.Series != nil && event.Series.LastObservedTime.After(lastSeen) {
+		lastSeen = event.Series.LastObservedTime.Time
+	}
+	if lastSeen.IsZero() {
+		lastSeen = event.EventTime.Time
+	}
+	if lastSeen.IsZero() {
+		lastSeen = event.CreationTimestamp.Time
+	}
+	return ClusterEvent{
+		Type:      event.Type,
+		Namespace: event.InvolvedObject.Namespace,
+		Kind:      event.InvolvedObject.Kind,
+		Name:      event.InvolvedObject.Name,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Count:     event.Count,
+		LastSeen:  lastSeen,
+	}
+}
+
+// eventDeduper remembers when each object/reason pair was last delivered
+type eventDeduper struct {
+	mu     sync.Mutex
+	window time.Duration
+	seen   map[string]time.Time
+}
+
+func newEventDeduper(window time.Duration) *eventDeduper {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	return &eventDeduper{window: window, seen: map[string]time.Time{}}
+}
+
+func (d *eventDeduper) allow(event ClusterEvent, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, last := range d.seen {
+		if now.Sub(last) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+	key := event.Namespace + "/" + event.Kind + "/" + event.Name + "/" + event.Reason
+	if _, ok := d.seen[key]; ok {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
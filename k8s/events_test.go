@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestEvent(name, kind, reason, eventType string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: "web-1", Namespace: "default"},
+		Reason:         reason,
+		Type:           eventType,
+		LastTimestamp:  metav1.Now(),
+		Count:          1,
+	}
+}
+
+func TestEventFilterMatches(t *testing.T) {
+	warning := ClusterEvent{Type: corev1.EventTypeWarning, Namespace: "default", Kind: "Pod", Reason: "BackOff"}
+	tests := []struct {
+		name   string
+		filter EventFilter
+		event  ClusterEvent
+		want   bool
+	}{
+		{name: "empty filter matches warnings", filter: EventFilter{}, event: warning, want: true},
+		{name: "normal events excluded by default", filter: EventFilter{}, event: ClusterEvent{Type: corev1.EventTypeNormal}, want: false},
+		{name: "namespace mismatch", filter: EventFilter{Namespace: "other"}, event: warning, want: false},
+		{name: "kind match", filter: EventFilter{Kinds: []string{"Node", "Pod"}}, event: warning, want: true},
+		{name: "reason mismatch", filter: EventFilter{Reasons: []string{"FailedScheduling"}}, event: warning, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(tt.event); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventDeduper(t *testing.T) {
+	dedup := newEventDeduper(time.Minute)
+	event := ClusterEvent{Namespace: "default", Kind: "Pod", Name: "web-1", Reason: "BackOff"}
+	now := time.Now()
+	if !dedup.allow(event, now) {
+		t.Error("allow() first event = false, want true")
+	}
+	if dedup.allow(event, now.Add(30*time.Second)) {
+		t.Error("allow() repeat inside window = true, want false")
+	}
+	if !dedup.allow(event, now.Add(2*time.Minute)) {
+		t.Error("allow() repeat after window = false, want true")
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	kube := &KubeClient{Clientset: clientset}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handled := make(chan ClusterEvent, 10)
+	events, err := kube.WatchEvents(ctx, EventFilter{Kinds: []string{"Pod"}}, func(e ClusterEvent) { handled <- e })
+	if err != nil {
+		t.Fatalf("WatchEvents() error = %v", err)
+	}
+
+	for _, event := range []*corev1.Event{
+		newTestEvent("normal", "Pod", "Pulled", corev1.EventTypeNormal),
+		newTestEvent("node", "Node", "NodeNotReady", corev1.EventTypeWarning),
+		newTestEvent("backoff-1", "Pod", "BackOff", corev1.EventTypeWarning),
+		newTestEvent("backoff-2", "Pod", "BackOff", corev1.EventTypeWarning),
+		newTestEvent("oom", "Pod", "OOMKilling", corev1.EventTypeWarning),
+	} {
+		event.LastTimestamp = metav1.NewTime(time.Now().Add(time.Second))
+		if _, err := clientset.CoreV1().Events("default").Create(context.TODO(), event, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	var reasons []string
+	timeout := time.After(5 * time.Second)
+	for len(reasons) < 2 {
+		select {
+		case event := <-events:
+			reasons = append(reasons, event.Reason)
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got %v", reasons)
+		}
+	}
+	if reasons[0] != "BackOff" || reasons[1] != "OOMKilling" {
+		t.Errorf("WatchEvents() reasons = %v, want [BackOff OOMKilling]", reasons)
+	}
+	if len(handled) != 2 {
+		t.Errorf("handler called %d times, want 2", len(handled))
+	}
+}
+
+func TestAsyncEventHandler(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	handler := asyncEventHandler(2, func(e ClusterEvent) {
+		<-release
+		handled <- e.Name
+	})
+
+	// The handler must not block on the slow sends, and drops events beyond the limit
+	done := make(chan struct{})
+	go func() {
+		for _, name := range []string{"a", "b", "c"} {
+			handler(ClusterEvent{Name: name})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler blocked while sends were pending")
+	}
+	close(release)
+
+	var names []string
+	for range 2 {
+		select {
+		case name := <-handled:
+			names = append(names, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for handled events, got %v", names)
+		}
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"a", "b"}) {
+		t.Errorf("handled = %v, want [a b]", names)
+	}
+}
@@ -0,0 +1,16 @@
+package k8s
+
+import (
+	"context"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// runInformer runs informer until ctx is cancelled and then closes out, which its handlers send on.
+// Run only returns once every handler has finished, so nothing can send on out after it is closed.
+func runInformer[T any](ctx context.Context, informer cache.SharedIndexInformer, out chan T) {
+	go func() {
+		informer.Run(ctx.Done())
+		close(out)
+	}()
+}
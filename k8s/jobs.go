@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JobCleanupPolicy decides which finished jobs CleanupJobs deletes, running jobs are never touched
+type JobCleanupPolicy struct {
+	TTL           time.Duration // Delete jobs that finished longer ago than this, 0 disables the age check
+	KeepLast      int           // Keep only the newest N finished jobs per group, 0 disables the count check
+	GroupByLabel  string        // Label whose value groups jobs for KeepLast, defaults to the owning CronJob
+	LabelSelector string        // Only consider jobs matching this selector
+	DryRun        bool          // Report what would be deleted without deleting it
+}
+
+// CleanupJobs deletes finished jobs older than the TTL or beyond the newest KeepLast of their group.
+// Pods are removed with the jobs through background propagation. The returned runs are the jobs deleted, or
+// that would be deleted in dry-run mode.
+func (k *KubeClient) CleanupJobs(ctx context.Context, namespace string, policy JobCleanupPolicy) ([]JobRun, error) {
+	if policy.TTL <= 0 && policy.KeepLast <= 0 {
+		return nil, fmt.Errorf("kube: job cleanup policy needs a TTL or KeepLast")
+	}
+	jobs, _, err := k.ListJobs(ctx, namespace, ListOptions{LabelSelector: policy.LabelSelector, All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	groups := map[string][]*batchv1.Job{}
+	for i := range jobs {
+		job := &jobs[i]
+		if jobOutcome(job) == JobRunning {
+			continue
+		}
+		key := job.Labels[policy.GroupByLabel]
+		if policy.GroupByLabel == "" {
+			if owner := metav1.GetControllerOf(job); owner != nil {
+				key = owner.Kind + "/" + owner.Name
+			}
+		}
+		groups[key] = append(groups[key], job)
+	}
+
+	var expired []*batchv1.Job
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool {
+			return jobFinishedAt(group[i]).After(jobFinishedAt(group[j]))
+		})
+		for i, job := range group {
+			tooMany := policy.KeepLast > 0 && i >= policy.KeepLast
+			tooOld := policy.TTL > 0 && now.Sub(jobFinishedAt(job)) > policy.TTL
+			if tooMany || tooOld {
+				expired = append(expired, job)
+			}
+		}
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	deleteOpts := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	var deleted []JobRun
+	for _, job := range expired {
+		if !policy.DryRun {
+			err := k.Clientset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, deleteOpts)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return deleted, fmt.Errorf("kube: unable to delete job %s: %w", job.Name, err)
+			}
+		}
+		deleted = append(deleted, jobRun(job))
+	}
+	slog.Info("kube: job cleanup finished", slog.String("Namespace", namespace), slog.Int("Jobs", len(deleted)), slog.Bool("DryRun", policy.DryRun))
+	return deleted, nil
+}
+
+// jobFinishedAt is when the job completed or failed, falling back to its creation time
+func jobFinishedAt(job *batchv1.Job) time.Time {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return job.CreationTimestamp.Time
+}
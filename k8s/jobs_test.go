@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFinishedJob(name, app string, age time.Duration, failed bool) *batchv1.Job {
+	finished := metav1.NewTime(time.Now().Add(-age))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": app}},
+	}
+	if failed {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: finished}}
+	} else {
+		job.Status.Succeeded = 1
+		job.Status.CompletionTime = &finished
+	}
+	return job
+}
+
+func TestCleanupJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy JobCleanupPolicy
+		want   []string
+	}{
+		{
+			name:   "ttl",
+			policy: JobCleanupPolicy{TTL: 24 * time.Hour},
+			want:   []string{"backup-old", "report-failed"},
+		},
+		{
+			name:   "keep last per label",
+			policy: JobCleanupPolicy{KeepLast: 1, GroupByLabel: "app"},
+			want:   []string{"backup-mid", "backup-old"},
+		},
+		{
+			name:   "label selector",
+			policy: JobCleanupPolicy{TTL: time.Hour, LabelSelector: "app=report"},
+			want:   []string{"report-failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s dryRun=%v", tt.name, dryRun), func(t *testing.T) {
+				running := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-running", Namespace: "default", Labels: map[string]string{"app": "backup"}}}
+				clientset := fake.NewSimpleClientset(
+					newFinishedJob("backup-new", "backup", time.Hour, false),
+					newFinishedJob("backup-mid", "backup", 2*time.Hour, false),
+					newFinishedJob("backup-old", "backup", 48*time.Hour, false),
+					newFinishedJob("report-failed", "report", 72*time.Hour, true),
+					running,
+				)
+				kube := &KubeClient{Clientset: clientset}
+				tt.policy.DryRun = dryRun
+
+				runs, err := kube.CleanupJobs(context.TODO(), "default", tt.policy)
+				if err != nil {
+					t.Fatalf("CleanupJobs() error = %v", err)
+				}
+				var got []string
+				for _, run := range runs {
+					got = append(got, run.Name)
+				}
+				sort.Strings(got)
+				if len(got) != len(tt.want) {
+					t.Fatalf("CleanupJobs() = %v, want %v", got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("CleanupJobs() = %v, want %v", got, tt.want)
+					}
+				}
+
+				remaining, _ := clientset.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+				wantRemaining := 5
+				if !dryRun {
+					wantRemaining -= len(tt.want)
+				}
+				if len(remaining.Items) != wantRemaining {
+					t.Errorf("remaining jobs = %d, want %d (dryRun %v)", len(remaining.Items), wantRemaining, dryRun)
+				}
+			})
+		}
+	}
+}
+
+func TestCleanupJobsRequiresPolicy(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	if _, err := kube.CleanupJobs(context.TODO(), "default", JobCleanupPolicy{}); err == nil {
+		t.Error("CleanupJobs() expected error for empty policy")
+	}
+}
@@ -7,23 +7,36 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 type KubeClient struct {
-	Clientset kubernetes.Interface `json:"-"` // Exclude from JSON
+	Config        *rest.Config               `json:"-"` // Exclude from JSON
+	Clientset     kubernetes.Interface       `json:"-"` // Exclude from JSON
+	DynamicClient dynamic.Interface          `json:"-"` // Exclude from JSON
+	Mapper        meta.RESTMapper            `json:"-"` // Exclude from JSON
+	MetricsClient metricsclientset.Interface `json:"-"` // Exclude from JSON
+
+	cache atomic.Pointer[kubeCache] // Set by EnableCache
 }
 
 // CheckClusterConnectivity checks the connectivity to the cluster
-func (k *KubeClient) CheckClusterConnectivity(env string) error { //V
-	_, err := k.Clientset.AppsV1().Deployments("kube-system").List(context.TODO(), metav1.ListOptions{})
+func (k *KubeClient) CheckClusterConnectivity(ctx context.Context, env string) error { //V
+	_, err := k.Clientset.AppsV1().Deployments("kube-system").List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("connectivity check failed: %v", err)
 	}
@@ -31,7 +44,7 @@ func (k *KubeClient) CheckClusterConnectivity(env string) error { //V
 }
 
 // Initialze Kubernetes Client per Cluster
-func (k *KubeClient) InitClient(env string) error { //V
+func (k *KubeClient) InitClient(ctx context.Context, env string) error { //V
 	var kubeconfigPath string
 	if runtime.GOOS == "windows" {
 		kubeconfigPath = filepath.Join(os.Getenv("USERPROFILE"), ".kube", "config")
@@ -42,11 +55,21 @@ func (k *KubeClient) InitClient(env string) error { //V
 	if err != nil {
 		log.Fatalf("Failed to load kubeconfig file: %v", err)
 	}
+	k.Config = config
 	k.Clientset, err = kubernetes.NewForConfig(config)
 	if err != nil {
 		return fmt.Errorf("unable to create %s client from config: %v", env, err)
 	}
-	err = k.CheckClusterConnectivity(env)
+	k.DynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("unable to create %s dynamic client from config: %v", env, err)
+	}
+	k.MetricsClient, err = metricsclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("unable to create %s metrics client from config: %v", env, err)
+	}
+	k.Mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(k.Clientset.Discovery()))
+	err = k.CheckClusterConnectivity(ctx, env)
 	if err != nil {
 		return fmt.Errorf("connection to %s cluster failed: %v", env, err)
 	}
@@ -54,29 +77,20 @@ func (k *KubeClient) InitClient(env string) error { //V
 }
 
 // Creates a Job from a CronJob
-func (k *KubeClient) TriggerJobFromCronJob(cronjobName, namespace string) (string, error) {
+func (k *KubeClient) TriggerJobFromCronJob(ctx context.Context, cronjobName, namespace string) (string, error) {
 	jobName := fmt.Sprintf("%s-api-trigger-%s", cronjobName, time.Now().Format("20060102150405"))
-	cronjob, err := k.Clientset.BatchV1().CronJobs(namespace).Get(context.TODO(), cronjobName, metav1.GetOptions{})
+	cronjob, err := k.Clientset.BatchV1().CronJobs(namespace).Get(ctx, cronjobName, metav1.GetOptions{})
 	if err != nil {
 		return cronjobName, fmt.Errorf("kube: unable to retrieve cronjob %v", err)
 	}
-	podTemplateSpec := cronjob.Spec.JobTemplate.Spec.Template
-	job := &batchv1.Job{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      jobName,
-			Namespace: namespace,
-		},
-		Spec: batchv1.JobSpec{
-			Template: podTemplateSpec,
-		},
-	}
-	_, err = k.Clientset.BatchV1().Jobs(namespace).Create(context.TODO(), job, metav1.CreateOptions{})
+	job := jobFromCronJob(cronjob, jobName)
+	_, err = k.Clientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
 		return cronjobName, fmt.Errorf("kube: unable to create job %v", err)
 	}
 	return jobName, nil
 }
-func (k *KubeClient) CreateJob(jobName, namespace, image string, commands []string, envVars, labels map[string]string, volumeNameP, mountPathP *string) error {
+func (k *KubeClient) CreateJob(ctx context.Context, jobName, namespace, image string, commands []string, envVars, labels map[string]string, volumeNameP, mountPathP *string) error {
 	var env []corev1.EnvVar
 	for key, value := range envVars {
 		env = append(env, corev1.EnvVar{
@@ -132,7 +146,7 @@ func (k *KubeClient) CreateJob(jobName, namespace, image string, commands []stri
 	}
 
 	// Create the Job in the specified namespace
-	_, err := k.Clientset.BatchV1().Jobs(namespace).Create(context.TODO(), job, metav1.CreateOptions{})
+	_, err := k.Clientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("kube: unable to create job %v", err)
 	}
@@ -141,14 +155,14 @@ func (k *KubeClient) CreateJob(jobName, namespace, image string, commands []stri
 }
 
 // ScaleDownDeployment scales down the deployment to 0 replicas
-func (k *KubeClient) ScaleDownDeployment(name, namespace string) error {
-	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+func (k *KubeClient) ScaleDownDeployment(ctx context.Context, name, namespace string) error {
+	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("kube: unable to retrieve deployment %v", err)
 	}
 	deployment.Spec.Replicas = new(int32)
 	*deployment.Spec.Replicas = 0
-	_, err = k.Clientset.AppsV1().Deployments(namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
+	_, err = k.Clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("kube: unable to scale down deployment %v", err)
 	}
@@ -156,14 +170,14 @@ func (k *KubeClient) ScaleDownDeployment(name, namespace string) error {
 }
 
 // ScaleUpDeployment scales up the deployment to the specified number of replicas
-func (k *KubeClient) ScaleUpDeployment(name, namespace string, replicas int32) error {
-	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+func (k *KubeClient) ScaleUpDeployment(ctx context.Context, name, namespace string, replicas int32) error {
+	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("kube: unable to retrieve deployment %v", err)
 	}
 	deployment.Spec.Replicas = new(int32)
 	*deployment.Spec.Replicas = replicas
-	_, err = k.Clientset.AppsV1().Deployments(namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
+	_, err = k.Clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("kube: unable to scale up deployment %v", err)
 	}
@@ -171,15 +185,15 @@ func (k *KubeClient) ScaleUpDeployment(name, namespace string, replicas int32) e
 }
 
 // ScaleDownDeploymentsInNamespace scales down all deployments in the namespace to 0 replicas
-func (k *KubeClient) ScaleDownAllDeploymentsInNamespace(namespace string) error {
-	deployments, err := k.Clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+func (k *KubeClient) ScaleDownAllDeploymentsInNamespace(ctx context.Context, namespace string) error {
+	deployments, _, err := k.ListDeployments(ctx, namespace, ListOptions{All: true})
 	if err != nil {
 		return fmt.Errorf("error getting %v deployments: %v", namespace, err)
 	}
-	replicas := int32(0)
-	for _, d := range deployments.Items {
-		d.Spec.Replicas = &replicas
-		_, err := k.Clientset.AppsV1().Deployments(namespace).Update(context.TODO(), &d, metav1.UpdateOptions{})
+	// The list may come from the cache, so patch only the replicas instead of writing back possibly stale objects
+	patch := []byte(`{"spec":{"replicas":0}}`)
+	for _, d := range deployments {
+		_, err := k.Clientset.AppsV1().Deployments(namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 		if err != nil {
 			return fmt.Errorf("error scaling down %s: %v", d.Name, err)
 		}
@@ -188,17 +202,17 @@ func (k *KubeClient) ScaleDownAllDeploymentsInNamespace(namespace string) error
 }
 
 // RestartDeployment restarts the deployment by updating the annotations
-func (k *KubeClient) RestartDeployment(deploymentName, namespace string) error {
+func (k *KubeClient) RestartDeployment(ctx context.Context, deploymentName, namespace string) error {
 	timestamp := time.Now().Format(time.RFC3339)
 	patchData := fmt.Sprintf(`{"spec": {"template": {"metadata": {"annotations": {"kubectl.kubernetes.io/restartedAt": "%s"}}}}}`, timestamp)
-	_, err := k.Clientset.AppsV1().Deployments(namespace).Patch(context.TODO(), deploymentName, types.StrategicMergePatchType, []byte(patchData), metav1.PatchOptions{})
+	_, err := k.Clientset.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.StrategicMergePatchType, []byte(patchData), metav1.PatchOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to patch deployment: %v", err)
 	}
 	return nil
 }
 
-func (k *KubeClient) CreateConfigMap(name, namespace string, data map[string]string) error {
+func (k *KubeClient) CreateConfigMap(ctx context.Context, name, namespace string, data map[string]string) error {
 	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -206,17 +220,17 @@ func (k *KubeClient) CreateConfigMap(name, namespace string, data map[string]str
 		},
 		Data: data,
 	}
-	_, err := k.Clientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+	_, err := k.Clientset.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("kube: unable to create configmap %v", err)
 	}
 	return nil
 }
 
-func (k *KubeClient) IsJobCompleted(jobName, namespace string) (bool, error) {
-	job, err := k.Clientset.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, metav1.GetOptions{})
+func (k *KubeClient) IsJobCompleted(ctx context.Context, jobName, namespace string) (bool, error) {
+	job, err := k.GetJob(ctx, jobName, namespace)
 	if err != nil {
-		return false, fmt.Errorf("kube: unable to retrieve job %v", err)
+		return false, err
 	}
 	if job.Status.Succeeded > 0 {
 		return true, nil
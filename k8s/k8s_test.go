@@ -49,7 +49,7 @@ func TestCheckClusterConnectivity(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			kube := tt.setup()
-			err := kube.CheckClusterConnectivity("test-env")
+			err := kube.CheckClusterConnectivity(context.TODO(), "test-env")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CheckClusterConnectivity() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -95,7 +95,7 @@ func TestTriggerJobFromCronJob(t *testing.T) {
 			}
 
 			kube := &KubeClient{Clientset: clientset}
-			_, err := kube.TriggerJobFromCronJob("test-cronjob", "default")
+			_, err := kube.TriggerJobFromCronJob(context.TODO(), "test-cronjob", "default")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("TriggerJobFromCronJob() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -139,7 +139,7 @@ func TestCreateJob(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			kube := tt.setup()
-			err := kube.CreateJob(tt.jobName, tt.namespace, "test-image", nil, nil, nil, nil, nil)
+			err := kube.CreateJob(context.TODO(), tt.jobName, tt.namespace, "test-image", nil, nil, nil, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateJob() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -182,7 +182,7 @@ func TestScaleDownDeployment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			kube := tt.setup()
-			err := kube.ScaleDownDeployment("test-deployment", "default")
+			err := kube.ScaleDownDeployment(context.TODO(), "test-deployment", "default")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ScaleDownDeployment() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -222,7 +222,7 @@ func TestRestartDeployment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			kube := tt.setup()
-			err := kube.RestartDeployment("test-deployment", "default")
+			err := kube.RestartDeployment(context.TODO(), "test-deployment", "default")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RestartDeployment() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -309,7 +309,7 @@ func TestIsJobCompleted(t *testing.T) {
 			kube := &KubeClient{Clientset: clientset}
 
 			// Call the method under test
-			got, err := kube.IsJobCompleted(tt.jobName, tt.namespace)
+			got, err := kube.IsJobCompleted(context.TODO(), tt.jobName, tt.namespace)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("IsJobCompleted() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -385,7 +385,7 @@ func TestCreateConfigMap(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			kube := tt.setup()
-			err := kube.CreateConfigMap(tt.configMap.Name, tt.configMap.Namespace, tt.configMap.Data)
+			err := kube.CreateConfigMap(context.TODO(), tt.configMap.Name, tt.configMap.Namespace, tt.configMap.Data)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateConfigMap() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// ErrLockLost is returned by WithLock when the lease expired before the guarded function returned
+var ErrLockLost = errors.New("kube: lock lost before the operation finished")
+
+// LeaderElectionOptions configures the Lease used by RunLeaderElection and WithLock
+type LeaderElectionOptions struct {
+	Namespace     string        // Namespace of the Lease, defaults to default
+	Identity      string        // Unique per replica, defaults to the hostname with a random suffix
+	LeaseDuration time.Duration // How long others wait before taking over an unrenewed lease, defaults to 15s
+	RenewDeadline time.Duration // How long the leader keeps retrying a renewal, defaults to 10s
+	RetryPeriod   time.Duration // Interval between acquire and renew attempts, defaults to 2s
+
+	OnStartedLeading func(ctx context.Context) // Runs when the lease is acquired, ctx is cancelled when it is lost
+	OnStoppedLeading func()                    // Runs when the lease is lost or released
+	OnNewLeader      func(identity string)     // Runs when any replica, including this one, becomes leader
+}
+
+// RunLeaderElection campaigns for the named Lease and calls the callbacks as leadership changes.
+// It blocks until ctx is cancelled, releasing the lease, or until leadership is lost; replicas usually exit
+// at that point so a fresh process rejoins the election.
+func (k *KubeClient) RunLeaderElection(ctx context.Context, name string, opts LeaderElectionOptions) error {
+	callbacks := leaderelection.LeaderCallbacks{
+		OnStartedLeading: opts.OnStartedLeading,
+		OnStoppedLeading: opts.OnStoppedLeading,
+		OnNewLeader:      opts.OnNewLeader,
+	}
+	if callbacks.OnStartedLeading == nil {
+		callbacks.OnStartedLeading = func(context.Context) {}
+	}
+	if callbacks.OnStoppedLeading == nil {
+		callbacks.OnStoppedLeading = func() {}
+	}
+	elector, _, err := k.newLeaderElector(name, &opts, callbacks)
+	if err != nil {
+		return err
+	}
+	slog.Info("kube: joining leader election", slog.String("Lease", name), slog.String("Identity", opts.Identity))
+	elector.Run(ctx)
+	return nil
+}
+
+// WithLock blocks until the named Lease is acquired, runs fn while holding it and releases it afterwards.
+// fn's context is cancelled if the lease is lost, in which case the returned error wraps ErrLockLost.
+func (k *KubeClient) WithLock(ctx context.Context, name string, opts LeaderElectionOptions, fn func(ctx context.Context) error) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	var fnErr error
+	var ran, lost bool
+	elector, lock, err := k.newLeaderElector(name, &opts, leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(leaderCtx context.Context) {
+			defer close(done)
+			// The elector starts this callback asynchronously, ctx may have been cancelled since the lease was acquired
+			if leaderCtx.Err() != nil {
+				return
+			}
+			ran = true
+			fnErr = fn(leaderCtx)
+			lost = leaderCtx.Err() != nil && ctx.Err() == nil
+			// Stop renewing, which releases the lease
+			cancel()
+		},
+		OnStoppedLeading: func() {},
+	})
+	if err != nil {
+		return err
+	}
+	elector.Run(runCtx)
+
+	// Once the lease was written the elector always starts OnStartedLeading, even if Run already returned
+	if !lock.acquired.Load() {
+		return fmt.Errorf("kube: unable to acquire lock %s: %w", name, ctx.Err())
+	}
+	<-done
+	switch {
+	case !ran:
+		return fmt.Errorf("kube: lock %s released before the operation started: %w", name, ctx.Err())
+	case lost && fnErr != nil:
+		return fmt.Errorf("%w: %w", ErrLockLost, fnErr)
+	case lost:
+		return ErrLockLost
+	}
+	return fnErr
+}
+
+// recordingLock notes when this replica writes itself into the lock as holder. The elector does that before
+// Run returns, unlike starting OnStartedLeading.
+type recordingLock struct {
+	resourcelock.Interface
+	acquired atomic.Bool
+}
+
+func (l *recordingLock) Create(ctx context.Context, record resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Create(ctx, record)
+	l.record(record, err)
+	return err
+}
+
+func (l *recordingLock) Update(ctx context.Context, record resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Update(ctx, record)
+	l.record(record, err)
+	return err
+}
+
+func (l *recordingLock) record(record resourcelock.LeaderElectionRecord, err error) {
+	if err == nil && record.HolderIdentity == l.Identity() {
+		l.acquired.Store(true)
+	}
+}
+
+// newLeaderElector builds an elector on a Lease, filling in defaults on opts
+func (k *KubeClient) newLeaderElector(name string, opts *LeaderElectionOptions, callbacks leaderelection.LeaderCallbacks) (*leaderelection.LeaderElector, *recordingLock, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = metav1.NamespaceDefault
+	}
+	if opts.Identity == "" {
+		hostname, _ := os.Hostname()
+		opts.Identity = hostname + "_" + string(uuid.NewUUID())
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = 15 * time.Second
+	}
+	if opts.RenewDeadline <= 0 {
+		opts.RenewDeadline = 10 * time.Second
+	}
+	if opts.RetryPeriod <= 0 {
+		opts.RetryPeriod = 2 * time.Second
+	}
+
+	lock := &recordingLock{Interface: &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: opts.Namespace},
+		Client:     k.Clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: opts.Identity},
+	}}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            name,
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks:       callbacks,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("kube: invalid leader election config for %s: %w", name, err)
+	}
+	return elector, lock, nil
+}
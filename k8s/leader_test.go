@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func fastLeaderElection(identity string) LeaderElectionOptions {
+	return LeaderElectionOptions{
+		Identity:      identity,
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   50 * time.Millisecond,
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	kube := &KubeClient{Clientset: clientset}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var active, maxActive, runs int32
+	var wg sync.WaitGroup
+	for _, identity := range []string{"replica-a", "replica-b", "replica-c"} {
+		wg.Add(1)
+		go func(identity string) {
+			defer wg.Done()
+			err := kube.WithLock(ctx, "scale-down", fastLeaderElection(identity), func(ctx context.Context) error {
+				n := atomic.AddInt32(&active, 1)
+				for {
+					m := atomic.LoadInt32(&maxActive)
+					if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+				atomic.AddInt32(&active, -1)
+				atomic.AddInt32(&runs, 1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("WithLock(%s) error = %v", identity, err)
+			}
+		}(identity)
+	}
+	wg.Wait()
+
+	if runs != 3 {
+		t.Errorf("guarded function ran %d times, want 3", runs)
+	}
+	if maxActive != 1 {
+		t.Errorf("%d replicas held the lock at once, want 1", maxActive)
+	}
+	lease, err := clientset.CoordinationV1().Leases("default").Get(context.TODO(), "scale-down", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease not created: %v", err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		t.Errorf("lease still held by %s after WithLock returned", *lease.Spec.HolderIdentity)
+	}
+}
+
+func TestWithLockReturnsError(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	want := errors.New("boom")
+	err := kube.WithLock(context.TODO(), "job", fastLeaderElection("replica-a"), func(ctx context.Context) error {
+		return want
+	})
+	if !errors.Is(err, want) || errors.Is(err, ErrLockLost) {
+		t.Errorf("WithLock() error = %v, want %v", err, want)
+	}
+}
+
+func TestWithLockCancelledBeforeAcquire(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Hold the lock from another replica until the second caller gives up
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = kube.WithLock(ctx, "job", fastLeaderElection("holder"), func(ctx context.Context) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+	defer close(release)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer waitCancel()
+	called := false
+	err := kube.WithLock(waitCtx, "job", fastLeaderElection("waiter"), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("WithLock() = %v, called %v, want timeout without running", err, called)
+	}
+}
+
+func TestWithLockCancelledAfterAcquire(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel as soon as the lease is created, so Run returns before the elector starts the callback
+	clientset.PrependReactor("create", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		cancel()
+		return false, nil, nil
+	})
+	kube := &KubeClient{Clientset: clientset}
+
+	var returned, ranAfterReturn atomic.Bool
+	err := kube.WithLock(ctx, "job", fastLeaderElection("replica-a"), func(ctx context.Context) error {
+		ranAfterReturn.Store(returned.Load())
+		return nil
+	})
+	returned.Store(true)
+	if err == nil {
+		t.Error("WithLock() expected error after cancellation")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ranAfterReturn.Load() {
+		t.Error("guarded function ran after WithLock returned")
+	}
+}
+
+func TestRunLeaderElection(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	started := make(chan struct{})
+	var stopped atomic.Bool
+	leaders := make(chan string, 1)
+	opts := fastLeaderElection("replica-a")
+	opts.OnStartedLeading = func(context.Context) { close(started) }
+	opts.OnStoppedLeading = func() { stopped.Store(true) }
+	opts.OnNewLeader = func(identity string) { leaders <- identity }
+
+	errs := make(chan error, 1)
+	go func() { errs <- kube.RunLeaderElection(ctx, "controller", opts) }()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leadership not acquired")
+	}
+	// OnNewLeader runs in its own goroutine
+	select {
+	case identity := <-leaders:
+		if identity != "replica-a" {
+			t.Errorf("OnNewLeader reported %s, want replica-a", identity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("OnNewLeader not called")
+	}
+	cancel()
+	if err := <-errs; err != nil {
+		t.Fatalf("RunLeaderElection() error = %v", err)
+	}
+	if !stopped.Load() {
+		t.Error("OnStoppedLeading not called after cancellation")
+	}
+}
+
+func TestRunLeaderElectionInvalidConfig(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	opts := LeaderElectionOptions{LeaseDuration: time.Second, RenewDeadline: 2 * time.Second}
+	if err := kube.RunLeaderElection(context.TODO(), "controller", opts); err == nil {
+		t.Error("RunLeaderElection() expected error when the renew deadline exceeds the lease duration")
+	}
+}
@@ -0,0 +1,145 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultPageSize is the page size used when ListOptions.All is set without a Limit
+const DefaultPageSize = 500
+
+// ListOptions selects and pages through namespaced resources, an empty namespace lists across all namespaces.
+// Pods, deployments and jobs are served from the cache when it is enabled and no field selector or continue token is set.
+type ListOptions struct {
+	LabelSelector string // e.g. app=web,tier!=cache
+	FieldSelector string // e.g. status.phase=Running
+	Limit         int64  // Page size, 0 lets the server return everything at once
+	Continue      string // Token returned by the previous page
+	All           bool   // Follow continue tokens until every page has been read
+}
+
+func (o ListOptions) listOptions() metav1.ListOptions {
+	opts := metav1.ListOptions{
+		LabelSelector: o.LabelSelector,
+		FieldSelector: o.FieldSelector,
+		Limit:         o.Limit,
+		Continue:      o.Continue,
+	}
+	if o.All && opts.Limit == 0 {
+		opts.Limit = DefaultPageSize
+	}
+	return opts
+}
+
+// ListPods lists pods, returning the items and the continue token for the next page
+func (k *KubeClient) ListPods(ctx context.Context, namespace string, opts ListOptions) ([]corev1.Pod, string, error) {
+	if c, selector, err := k.cachedList(namespace, opts); c != nil || err != nil {
+		if err != nil {
+			return nil, "", err
+		}
+		var pods []*corev1.Pod
+		if namespace == metav1.NamespaceAll {
+			pods, err = c.pods.List(selector)
+		} else {
+			pods, err = c.pods.Pods(namespace).List(selector)
+		}
+		return copyItems(pods), "", err
+	}
+	return paginate(ctx, opts, "pods", func(ctx context.Context, listOpts metav1.ListOptions) ([]corev1.Pod, string, error) {
+		list, err := k.Clientset.CoreV1().Pods(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		return list.Items, list.Continue, nil
+	})
+}
+
+// ListDeployments lists deployments, returning the items and the continue token for the next page
+func (k *KubeClient) ListDeployments(ctx context.Context, namespace string, opts ListOptions) ([]appsv1.Deployment, string, error) {
+	if c, selector, err := k.cachedList(namespace, opts); c != nil || err != nil {
+		if err != nil {
+			return nil, "", err
+		}
+		var deployments []*appsv1.Deployment
+		if namespace == metav1.NamespaceAll {
+			deployments, err = c.deployments.List(selector)
+		} else {
+			deployments, err = c.deployments.Deployments(namespace).List(selector)
+		}
+		return copyItems(deployments), "", err
+	}
+	return paginate(ctx, opts, "deployments", func(ctx context.Context, listOpts metav1.ListOptions) ([]appsv1.Deployment, string, error) {
+		list, err := k.Clientset.AppsV1().Deployments(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		return list.Items, list.Continue, nil
+	})
+}
+
+// ListJobs lists jobs, returning the items and the continue token for the next page
+func (k *KubeClient) ListJobs(ctx context.Context, namespace string, opts ListOptions) ([]batchv1.Job, string, error) {
+	if c, selector, err := k.cachedList(namespace, opts); c != nil || err != nil {
+		if err != nil {
+			return nil, "", err
+		}
+		var jobs []*batchv1.Job
+		if namespace == metav1.NamespaceAll {
+			jobs, err = c.jobs.List(selector)
+		} else {
+			jobs, err = c.jobs.Jobs(namespace).List(selector)
+		}
+		return copyItems(jobs), "", err
+	}
+	return paginate(ctx, opts, "jobs", func(ctx context.Context, listOpts metav1.ListOptions) ([]batchv1.Job, string, error) {
+		list, err := k.Clientset.BatchV1().Jobs(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		return list.Items, list.Continue, nil
+	})
+}
+
+// ListServices lists services, returning the items and the continue token for the next page
+func (k *KubeClient) ListServices(ctx context.Context, namespace string, opts ListOptions) ([]corev1.Service, string, error) {
+	return paginate(ctx, opts, "services", func(ctx context.Context, listOpts metav1.ListOptions) ([]corev1.Service, string, error) {
+		list, err := k.Clientset.CoreV1().Services(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		return list.Items, list.Continue, nil
+	})
+}
+
+// ListPVCs lists persistent volume claims, returning the items and the continue token for the next page
+func (k *KubeClient) ListPVCs(ctx context.Context, namespace string, opts ListOptions) ([]corev1.PersistentVolumeClaim, string, error) {
+	return paginate(ctx, opts, "persistent volume claims", func(ctx context.Context, listOpts metav1.ListOptions) ([]corev1.PersistentVolumeClaim, string, error) {
+		list, err := k.Clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		return list.Items, list.Continue, nil
+	})
+}
+
+// paginate reads one page, or every page when opts.All is set
+func paginate[T any](ctx context.Context, opts ListOptions, resource string, list func(context.Context, metav1.ListOptions) ([]T, string, error)) ([]T, string, error) {
+	listOpts := opts.listOptions()
+	var items []T
+	for {
+		page, next, err := list(ctx, listOpts)
+		if err != nil {
+			return nil, "", fmt.Errorf("kube: unable to list %s: %w", resource, err)
+		}
+		items = append(items, page...)
+		if !opts.All || next == "" {
+			return items, next, nil
+		}
+		listOpts.Continue = next
+	}
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newPagedClientset serves three pods two at a time, honouring the continue token
+func newPagedClientset() *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "default"}},
+	}
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.ListActionImpl).ListOptions
+		if opts.Limit != 2 {
+			return true, nil, fmt.Errorf("unexpected limit %d", opts.Limit)
+		}
+		if opts.Continue == "" {
+			return true, &corev1.PodList{ListMeta: metav1.ListMeta{Continue: "page-2"}, Items: pods[:2]}, nil
+		}
+		return true, &corev1.PodList{Items: pods[2:]}, nil
+	})
+	return clientset
+}
+
+func TestListPodsPagination(t *testing.T) {
+	kube := &KubeClient{Clientset: newPagedClientset()}
+
+	page, next, err := kube.ListPods(context.TODO(), "default", ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListPods() error = %v", err)
+	}
+	if len(page) != 2 || next != "page-2" {
+		t.Errorf("ListPods() first page = %d items, continue %q, want 2 items and page-2", len(page), next)
+	}
+	page, next, err = kube.ListPods(context.TODO(), "default", ListOptions{Limit: 2, Continue: next})
+	if err != nil || len(page) != 1 || next != "" {
+		t.Errorf("ListPods() second page = %d items, continue %q, err %v", len(page), next, err)
+	}
+
+	all, next, err := kube.ListPods(context.TODO(), "default", ListOptions{Limit: 2, All: true})
+	if err != nil || len(all) != 3 || next != "" {
+		t.Errorf("ListPods() all = %d items, continue %q, err %v, want 3 items", len(all), next, err)
+	}
+}
+
+func TestListDeploymentsLabelSelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"tier": "web"}}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"tier": "db"}}},
+	)
+	kube := &KubeClient{Clientset: clientset}
+
+	deployments, _, err := kube.ListDeployments(context.TODO(), "default", ListOptions{LabelSelector: "tier=web"})
+	if err != nil {
+		t.Fatalf("ListDeployments() error = %v", err)
+	}
+	if len(deployments) != 1 || deployments[0].Name != "web" {
+		t.Errorf("ListDeployments() = %v, want only web", deployments)
+	}
+}
+
+func TestListErrors(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("simulated list failure")
+	})
+	kube := &KubeClient{Clientset: clientset}
+
+	if _, _, err := kube.ListJobs(context.TODO(), "default", ListOptions{}); err == nil {
+		t.Error("ListJobs() expected error")
+	}
+	if _, _, err := kube.ListServices(context.TODO(), "default", ListOptions{}); err == nil {
+		t.Error("ListServices() expected error")
+	}
+	if _, _, err := kube.ListPVCs(context.TODO(), "default", ListOptions{All: true}); err == nil {
+		t.Error("ListPVCs() expected error")
+	}
+}
@@ -0,0 +1,280 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// DefaultFieldManager is the field manager used for server-side apply when none is set
+const DefaultFieldManager = "itsvictorfy-pkg"
+
+// ManifestAction describes what happened (or would happen) to a single manifest object
+type ManifestAction string
+
+const (
+	ManifestCreated    ManifestAction = "created"
+	ManifestConfigured ManifestAction = "configured"
+	ManifestUnchanged  ManifestAction = "unchanged"
+	ManifestDeleted    ManifestAction = "deleted"
+	ManifestNotFound   ManifestAction = "not found"
+)
+
+// ManifestOptions controls how manifests are applied or deleted
+type ManifestOptions struct {
+	FieldManager string // Server-side apply field manager, defaults to DefaultFieldManager
+	Namespace    string // Namespace for namespaced objects that don't set one, defaults to "default"
+	Force        bool   // Take ownership of fields owned by other managers
+	DryRun       bool   // Ask the API server to evaluate the request without persisting it
+}
+
+// ManifestResult is the outcome of applying or deleting a single object
+type ManifestResult struct {
+	GroupVersionKind schema.GroupVersionKind `json:"gvk"`
+	Namespace        string                  `json:"namespace,omitempty"`
+	Name             string                  `json:"name"`
+	Action           ManifestAction          `json:"action"`
+	Changes          []string                `json:"changes,omitempty"` // Field paths that differ from the live object
+}
+
+func (r ManifestResult) String() string {
+	ref := r.GroupVersionKind.Kind + "/" + r.Name
+	if r.Namespace != "" {
+		ref = r.Namespace + "/" + ref
+	}
+	return fmt.Sprintf("%s %s", ref, r.Action)
+}
+
+// ApplyManifests decodes a multi-document YAML or JSON stream and server-side applies every object in it
+func (k *KubeClient) ApplyManifests(ctx context.Context, reader io.Reader, opts ManifestOptions) ([]ManifestResult, error) {
+	objects, err := DecodeManifests(reader)
+	if err != nil {
+		return nil, err
+	}
+	applyOpts := metav1.ApplyOptions{
+		FieldManager: opts.FieldManager,
+		Force:        opts.Force,
+	}
+	if applyOpts.FieldManager == "" {
+		applyOpts.FieldManager = DefaultFieldManager
+	}
+	if opts.DryRun {
+		applyOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	results := make([]ManifestResult, 0, len(objects))
+	for _, obj := range objects {
+		resource, err := k.resourceFor(obj, opts.Namespace)
+		if err != nil {
+			return results, err
+		}
+		result := ManifestResult{
+			GroupVersionKind: obj.GroupVersionKind(),
+			Namespace:        obj.GetNamespace(),
+			Name:             obj.GetName(),
+		}
+
+		live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			live = nil
+		} else if err != nil {
+			return results, fmt.Errorf("kube: unable to retrieve %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		applied, err := resource.Apply(ctx, obj.GetName(), obj, applyOpts)
+		if err != nil {
+			return results, fmt.Errorf("kube: unable to apply %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		if live == nil {
+			result.Action = ManifestCreated
+		} else {
+			result.Changes = diffObjects(live.Object, applied.Object)
+			result.Action = ManifestUnchanged
+			if len(result.Changes) > 0 {
+				result.Action = ManifestConfigured
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// DeleteManifests deletes every object described in the stream, in reverse order of appearance
+func (k *KubeClient) DeleteManifests(ctx context.Context, reader io.Reader, opts ManifestOptions) ([]ManifestResult, error) {
+	objects, err := DecodeManifests(reader)
+	if err != nil {
+		return nil, err
+	}
+	propagation := metav1.DeletePropagationBackground
+	deleteOpts := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	if opts.DryRun {
+		deleteOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	results := make([]ManifestResult, 0, len(objects))
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		resource, err := k.resourceFor(obj, opts.Namespace)
+		if err != nil {
+			return results, err
+		}
+		result := ManifestResult{
+			GroupVersionKind: obj.GroupVersionKind(),
+			Namespace:        obj.GetNamespace(),
+			Name:             obj.GetName(),
+			Action:           ManifestDeleted,
+		}
+		err = resource.Delete(ctx, obj.GetName(), deleteOpts)
+		if apierrors.IsNotFound(err) {
+			result.Action = ManifestNotFound
+		} else if err != nil {
+			return results, fmt.Errorf("kube: unable to delete %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// DecodeManifests splits a YAML or JSON stream into objects, expanding List kinds and skipping empty documents
+func DecodeManifests(reader io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(reader, 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		raw := map[string]interface{}{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("kube: unable to decode manifest: %w", err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("kube: unable to decode %s: %w", obj.GetKind(), err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("kube: manifest %q is missing apiVersion or kind", obj.GetName())
+		}
+		if obj.GetName() == "" {
+			return nil, fmt.Errorf("kube: %s manifest is missing metadata.name", obj.GetKind())
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// resourceFor resolves the dynamic client for an object, defaulting its namespace when it is namespaced
+func (k *KubeClient) resourceFor(obj *unstructured.Unstructured, namespace string) (dynamic.ResourceInterface, error) {
+	if k.DynamicClient == nil {
+		return nil, fmt.Errorf("kube: dynamic client is not initialized")
+	}
+	mapping, err := k.restMapping(obj.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+		return k.DynamicClient.Resource(mapping.Resource), nil
+	}
+	if obj.GetNamespace() == "" {
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		obj.SetNamespace(namespace)
+	}
+	return k.DynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+// restMapping looks up the resource for a kind, refreshing discovery once so freshly created CRDs resolve
+func (k *KubeClient) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if k.Mapper == nil {
+		k.Mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(k.Clientset.Discovery()))
+	}
+	mapping, err := k.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		if resettable, ok := k.Mapper.(meta.ResettableRESTMapper); ok {
+			resettable.Reset()
+			mapping, err = k.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to resolve resource for %s: %w", gvk, err)
+	}
+	return mapping, nil
+}
+
+// diffObjects returns the sorted field paths that differ between two objects, ignoring server-managed metadata and status
+func diffObjects(before, after map[string]interface{}) []string {
+	before = stripServerFields(before)
+	after = stripServerFields(after)
+	var changes []string
+	diffValues("", before, after, &changes)
+	sort.Strings(changes)
+	return changes
+}
+
+func diffValues(path string, before, after interface{}, changes *[]string) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	// An absent field and an empty object are equivalent for comparison purposes
+	if before == nil && afterIsMap {
+		beforeMap, beforeIsMap = map[string]interface{}{}, true
+	}
+	if after == nil && beforeIsMap {
+		afterMap, afterIsMap = map[string]interface{}{}, true
+	}
+	if !beforeIsMap || !afterIsMap {
+		if !reflect.DeepEqual(before, after) {
+			*changes = append(*changes, path)
+		}
+		return
+	}
+	keys := map[string]struct{}{}
+	for key := range beforeMap {
+		keys[key] = struct{}{}
+	}
+	for key := range afterMap {
+		keys[key] = struct{}{}
+	}
+	for key := range keys {
+		child := key
+		if path != "" {
+			child = path + "." + key
+		}
+		diffValues(child, beforeMap[key], afterMap[key], changes)
+	}
+}
+
+func stripServerFields(obj map[string]interface{}) map[string]interface{} {
+	copied := runtime.DeepCopyJSON(obj)
+	delete(copied, "status")
+	unstructured.RemoveNestedField(copied, "metadata", "managedFields")
+	unstructured.RemoveNestedField(copied, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(copied, "metadata", "generation")
+	unstructured.RemoveNestedField(copied, "metadata", "uid")
+	unstructured.RemoveNestedField(copied, "metadata", "creationTimestamp")
+	return copied
+}
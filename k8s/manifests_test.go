@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testManifests = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant
+---
+# empty documents are skipped
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: tenant
+data:
+  key: new-value
+`
+
+func newManifestClient() *KubeClient {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "tenant"},
+			Data:       map[string]string{"key": "old-value"},
+		},
+	)
+	// The fake tracker applies via strategic merge, which unstructured objects don't support,
+	// so treat an apply as a full replacement of the stored object
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := dynamicClient.Tracker()
+		if err := tracker.Update(patch.GetResource(), obj, patch.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		stored, err := tracker.Get(patch.GetResource(), patch.GetNamespace(), patch.GetName())
+		return true, stored, err
+	})
+	return &KubeClient{Clientset: fake.NewSimpleClientset(), DynamicClient: dynamicClient, Mapper: mapper}
+}
+
+func TestDecodeManifests(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "multi document yaml", input: testManifests, want: 2},
+		{name: "json list", input: `{"apiVersion":"v1","kind":"List","items":[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}]}`, want: 1},
+		{name: "missing kind", input: "apiVersion: v1\nmetadata:\n  name: a\n", wantErr: true},
+		{name: "missing name", input: "apiVersion: v1\nkind: ConfigMap\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := DecodeManifests(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeManifests() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(objects) != tt.want {
+				t.Errorf("DecodeManifests() got %d objects, want %d", len(objects), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyManifests(t *testing.T) {
+	kube := newManifestClient()
+	results, err := kube.ApplyManifests(context.TODO(), strings.NewReader(testManifests), ManifestOptions{})
+	if err != nil {
+		t.Fatalf("ApplyManifests() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ApplyManifests() got %d results, want 2", len(results))
+	}
+	if results[0].Action != ManifestUnchanged {
+		t.Errorf("namespace action = %v, want %v", results[0].Action, ManifestUnchanged)
+	}
+	if results[1].Action != ManifestConfigured || len(results[1].Changes) != 1 || results[1].Changes[0] != "data.key" {
+		t.Errorf("configmap result = %+v, want configured with data.key changed", results[1])
+	}
+}
+
+func TestApplyManifestsUnknownKind(t *testing.T) {
+	kube := newManifestClient()
+	input := "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: a\n"
+	if _, err := kube.ApplyManifests(context.TODO(), strings.NewReader(input), ManifestOptions{}); err == nil {
+		t.Error("ApplyManifests() expected error for unknown kind")
+	}
+}
+
+func TestDeleteManifests(t *testing.T) {
+	kube := newManifestClient()
+	input := testManifests + "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: missing\n"
+	results, err := kube.DeleteManifests(context.TODO(), strings.NewReader(input), ManifestOptions{Namespace: "tenant"})
+	if err != nil {
+		t.Fatalf("DeleteManifests() error = %v", err)
+	}
+	want := []ManifestAction{ManifestNotFound, ManifestDeleted, ManifestDeleted}
+	if len(results) != len(want) {
+		t.Fatalf("DeleteManifests() got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Action != want[i] {
+			t.Errorf("result %d (%s) action = %v, want %v", i, result.Name, result.Action, want[i])
+		}
+	}
+}
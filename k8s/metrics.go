@@ -0,0 +1,262 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Provisioning states reported by ProvisioningReport
+const (
+	ProvisioningOK         = "ok"
+	ProvisioningOver       = "over-provisioned"  // Using far less than requested
+	ProvisioningUnder      = "under-provisioned" // Using close to or more than requested
+	ProvisioningNoRequests = "no-requests"       // No CPU or memory requests to compare against
+)
+
+// ContainerUsage is the current usage of a container next to its requests and limits
+type ContainerUsage struct {
+	Name          string            `json:"name"`
+	CPU           resource.Quantity `json:"cpu"`
+	Memory        resource.Quantity `json:"memory"`
+	CPURequest    resource.Quantity `json:"cpuRequest"`
+	CPULimit      resource.Quantity `json:"cpuLimit"`
+	MemoryRequest resource.Quantity `json:"memoryRequest"`
+	MemoryLimit   resource.Quantity `json:"memoryLimit"`
+}
+
+// PodUsage is the usage of every container of a pod at the time the metrics were scraped
+type PodUsage struct {
+	Namespace  string           `json:"namespace"`
+	Name       string           `json:"name"`
+	Workload   string           `json:"workload"` // Owning controller as Kind/Name, the pod itself when unowned
+	Timestamp  time.Time        `json:"timestamp"`
+	Containers []ContainerUsage `json:"containers"`
+}
+
+// NodeUsage is the usage of a node against its allocatable capacity
+type NodeUsage struct {
+	Name              string            `json:"name"`
+	CPU               resource.Quantity `json:"cpu"`
+	Memory            resource.Quantity `json:"memory"`
+	CPUAllocatable    resource.Quantity `json:"cpuAllocatable"`
+	MemoryAllocatable resource.Quantity `json:"memoryAllocatable"`
+	CPUPercent        float64           `json:"cpuPercent"`
+	MemoryPercent     float64           `json:"memoryPercent"`
+}
+
+// ProvisioningOptions sets the usage to request ratios that count as over or under provisioned
+type ProvisioningOptions struct {
+	LowUtilization  float64 // Below this on both CPU and memory is over-provisioned, defaults to 0.3
+	HighUtilization float64 // Above this on CPU or memory is under-provisioned, defaults to 0.9
+}
+
+// WorkloadUsage sums the usage, requests and limits of all pods of a workload
+type WorkloadUsage struct {
+	Namespace         string            `json:"namespace"`
+	Workload          string            `json:"workload"`
+	Pods              int               `json:"pods"`
+	CPU               resource.Quantity `json:"cpu"`
+	CPURequest        resource.Quantity `json:"cpuRequest"`
+	CPULimit          resource.Quantity `json:"cpuLimit"`
+	Memory            resource.Quantity `json:"memory"`
+	MemoryRequest     resource.Quantity `json:"memoryRequest"`
+	MemoryLimit       resource.Quantity `json:"memoryLimit"`
+	CPUUtilization    float64           `json:"cpuUtilization"`    // Usage divided by requests, 0 without requests
+	MemoryUtilization float64           `json:"memoryUtilization"` // Usage divided by requests, 0 without requests
+	Status            string            `json:"status"`
+}
+
+// PodMetrics returns the usage of the pods in a namespace from the metrics API, joined with their requests and limits.
+// An empty namespace covers all namespaces.
+func (k *KubeClient) PodMetrics(ctx context.Context, namespace, labelSelector string) ([]PodUsage, error) {
+	if k.MetricsClient == nil {
+		return nil, fmt.Errorf("kube: metrics client is not initialized")
+	}
+	metrics, err := k.MetricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve pod metrics: %w", err)
+	}
+	pods, _, err := k.ListPods(ctx, namespace, ListOptions{LabelSelector: labelSelector, All: true})
+	if err != nil {
+		return nil, err
+	}
+	podsByName := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podsByName[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+
+	usage := make([]PodUsage, 0, len(metrics.Items))
+	for _, m := range metrics.Items {
+		pod, ok := podsByName[m.Namespace+"/"+m.Name]
+		if !ok {
+			// The pod was deleted since the metrics were scraped
+			continue
+		}
+		podUsage := PodUsage{
+			Namespace: m.Namespace,
+			Name:      m.Name,
+			Workload:  podWorkload(pod),
+			Timestamp: m.Timestamp.Time,
+		}
+		for _, c := range m.Containers {
+			containerUsage := ContainerUsage{
+				Name:   c.Name,
+				CPU:    c.Usage[corev1.ResourceCPU],
+				Memory: c.Usage[corev1.ResourceMemory],
+			}
+			for _, spec := range pod.Spec.Containers {
+				if spec.Name == c.Name {
+					containerUsage.CPURequest = spec.Resources.Requests[corev1.ResourceCPU]
+					containerUsage.CPULimit = spec.Resources.Limits[corev1.ResourceCPU]
+					containerUsage.MemoryRequest = spec.Resources.Requests[corev1.ResourceMemory]
+					containerUsage.MemoryLimit = spec.Resources.Limits[corev1.ResourceMemory]
+				}
+			}
+			podUsage.Containers = append(podUsage.Containers, containerUsage)
+		}
+		usage = append(usage, podUsage)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Namespace != usage[j].Namespace {
+			return usage[i].Namespace < usage[j].Namespace
+		}
+		return usage[i].Name < usage[j].Name
+	})
+	return usage, nil
+}
+
+// NodeMetrics returns the usage of every node from the metrics API next to its allocatable capacity
+func (k *KubeClient) NodeMetrics(ctx context.Context) ([]NodeUsage, error) {
+	if k.MetricsClient == nil {
+		return nil, fmt.Errorf("kube: metrics client is not initialized")
+	}
+	metrics, err := k.MetricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve node metrics: %w", err)
+	}
+	nodes, err := k.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to list nodes: %w", err)
+	}
+	allocatable := make(map[string]corev1.ResourceList, len(nodes.Items))
+	for _, node := range nodes.Items {
+		allocatable[node.Name] = node.Status.Allocatable
+	}
+
+	usage := make([]NodeUsage, 0, len(metrics.Items))
+	for _, m := range metrics.Items {
+		nodeUsage := NodeUsage{
+			Name:              m.Name,
+			CPU:               m.Usage[corev1.ResourceCPU],
+			Memory:            m.Usage[corev1.ResourceMemory],
+			CPUAllocatable:    allocatable[m.Name][corev1.ResourceCPU],
+			MemoryAllocatable: allocatable[m.Name][corev1.ResourceMemory],
+		}
+		nodeUsage.CPUPercent = 100 * ratio(nodeUsage.CPU, nodeUsage.CPUAllocatable)
+		nodeUsage.MemoryPercent = 100 * ratio(nodeUsage.Memory, nodeUsage.MemoryAllocatable)
+		usage = append(usage, nodeUsage)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Name < usage[j].Name })
+	return usage, nil
+}
+
+// ProvisioningReport groups pod usage by workload and flags workloads whose requests are far from their usage
+func (k *KubeClient) ProvisioningReport(ctx context.Context, namespace string, opts ProvisioningOptions) ([]WorkloadUsage, error) {
+	if opts.LowUtilization <= 0 {
+		opts.LowUtilization = 0.3
+	}
+	if opts.HighUtilization <= 0 {
+		opts.HighUtilization = 0.9
+	}
+	pods, err := k.PodMetrics(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := map[string]*WorkloadUsage{}
+	var order []string
+	for _, pod := range pods {
+		key := pod.Namespace + "/" + pod.Workload
+		w, ok := workloads[key]
+		if !ok {
+			w = &WorkloadUsage{Namespace: pod.Namespace, Workload: pod.Workload}
+			workloads[key] = w
+			order = append(order, key)
+		}
+		w.Pods++
+		for _, c := range pod.Containers {
+			w.CPU.Add(c.CPU)
+			w.CPURequest.Add(c.CPURequest)
+			w.CPULimit.Add(c.CPULimit)
+			w.Memory.Add(c.Memory)
+			w.MemoryRequest.Add(c.MemoryRequest)
+			w.MemoryLimit.Add(c.MemoryLimit)
+		}
+	}
+
+	report := make([]WorkloadUsage, 0, len(order))
+	for _, key := range order {
+		w := workloads[key]
+		w.CPUUtilization = ratio(w.CPU, w.CPURequest)
+		w.MemoryUtilization = ratio(w.Memory, w.MemoryRequest)
+		w.Status = provisioningStatus(w, opts)
+		report = append(report, *w)
+	}
+	return report, nil
+}
+
+// provisioningStatus compares the utilization of the resources that have requests against the thresholds
+func provisioningStatus(w *WorkloadUsage, opts ProvisioningOptions) string {
+	var utilizations []float64
+	if !w.CPURequest.IsZero() {
+		utilizations = append(utilizations, w.CPUUtilization)
+	}
+	if !w.MemoryRequest.IsZero() {
+		utilizations = append(utilizations, w.MemoryUtilization)
+	}
+	if len(utilizations) == 0 {
+		return ProvisioningNoRequests
+	}
+	over := true
+	for _, u := range utilizations {
+		if u > opts.HighUtilization {
+			return ProvisioningUnder
+		}
+		if u >= opts.LowUtilization {
+			over = false
+		}
+	}
+	if over {
+		return ProvisioningOver
+	}
+	return ProvisioningOK
+}
+
+// podWorkload names the controller that owns the pod, resolving ReplicaSets to their Deployment by the template hash
+func podWorkload(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod/" + pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind + "/" + owner.Name
+}
+
+func ratio(usage, capacity resource.Quantity) float64 {
+	if capacity.IsZero() {
+		return 0
+	}
+	return float64(usage.MilliValue()) / float64(capacity.MilliValue())
+}
@@ -0,0 +1,158 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func newMetricsPod(name, replicaSet, hash, cpuRequest, memoryRequest string) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "default",
+			Labels:          map[string]string{"pod-template-hash": hash},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: replicaSet, Controller: &controller}},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpuRequest),
+					corev1.ResourceMemory: resource.MustParse(memoryRequest),
+				},
+			},
+		}}},
+	}
+}
+
+func newPodMetrics(name, cpu, memory string) metricsv1beta1.PodMetrics {
+	return metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name: "app",
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		}},
+	}
+}
+
+// newMetricsClient serves the given pod and node metrics, the fake tracker can't map the metrics kinds to their resources
+func newMetricsClient(pods []metricsv1beta1.PodMetrics, nodes []metricsv1beta1.NodeMetrics) *metricsfake.Clientset {
+	client := &metricsfake.Clientset{}
+	client.AddReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: pods}, nil
+	})
+	client.AddReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.NodeMetricsList{Items: nodes}, nil
+	})
+	return client
+}
+
+func TestPodMetrics(t *testing.T) {
+	kube := &KubeClient{
+		Clientset: fake.NewSimpleClientset(newMetricsPod("web-7d9-a", "web-7d9", "7d9", "500m", "256Mi")),
+		MetricsClient: newMetricsClient([]metricsv1beta1.PodMetrics{
+			newPodMetrics("web-7d9-a", "100m", "64Mi"),
+			newPodMetrics("deleted", "100m", "64Mi"),
+		}, nil),
+	}
+
+	usage, err := kube.PodMetrics(context.TODO(), "default", "")
+	if err != nil {
+		t.Fatalf("PodMetrics() error = %v", err)
+	}
+	if len(usage) != 1 {
+		t.Fatalf("PodMetrics() returned %d pods, want 1", len(usage))
+	}
+	if usage[0].Workload != "Deployment/web" {
+		t.Errorf("Workload = %s, want Deployment/web", usage[0].Workload)
+	}
+	c := usage[0].Containers[0]
+	if c.CPU.String() != "100m" || c.CPURequest.String() != "500m" || c.MemoryRequest.String() != "256Mi" {
+		t.Errorf("container usage = %+v", c)
+	}
+}
+
+func TestNodeMetrics(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("4"),
+			corev1.ResourceMemory: resource.MustParse("8Gi"),
+		}},
+	}
+	kube := &KubeClient{
+		Clientset: fake.NewSimpleClientset(node),
+		MetricsClient: newMetricsClient(nil, []metricsv1beta1.NodeMetrics{{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("6Gi"),
+			},
+		}}),
+	}
+
+	usage, err := kube.NodeMetrics(context.TODO())
+	if err != nil {
+		t.Fatalf("NodeMetrics() error = %v", err)
+	}
+	if len(usage) != 1 || usage[0].CPUPercent != 25 || usage[0].MemoryPercent != 75 {
+		t.Errorf("NodeMetrics() = %+v, want 25%% CPU and 75%% memory", usage)
+	}
+}
+
+func TestProvisioningReport(t *testing.T) {
+	kube := &KubeClient{
+		Clientset: fake.NewSimpleClientset(
+			newMetricsPod("idle-1-a", "idle-1", "1", "1", "1Gi"),
+			newMetricsPod("idle-1-b", "idle-1", "1", "1", "1Gi"),
+			newMetricsPod("busy-2-a", "busy-2", "2", "100m", "128Mi"),
+			newMetricsPod("fine-3-a", "fine-3", "3", "200m", "256Mi"),
+		),
+		MetricsClient: newMetricsClient([]metricsv1beta1.PodMetrics{
+			newPodMetrics("idle-1-a", "10m", "100Mi"),
+			newPodMetrics("idle-1-b", "10m", "100Mi"),
+			newPodMetrics("busy-2-a", "95m", "64Mi"),
+			newPodMetrics("fine-3-a", "100m", "128Mi"),
+		}, nil),
+	}
+
+	report, err := kube.ProvisioningReport(context.TODO(), "default", ProvisioningOptions{})
+	if err != nil {
+		t.Fatalf("ProvisioningReport() error = %v", err)
+	}
+	want := map[string]string{
+		"Deployment/idle": ProvisioningOver,
+		"Deployment/busy": ProvisioningUnder,
+		"Deployment/fine": ProvisioningOK,
+	}
+	if len(report) != len(want) {
+		t.Fatalf("ProvisioningReport() returned %d workloads, want %d", len(report), len(want))
+	}
+	for _, w := range report {
+		if w.Status != want[w.Workload] {
+			t.Errorf("%s status = %s, want %s", w.Workload, w.Status, want[w.Workload])
+		}
+		if w.Workload == "Deployment/idle" && (w.Pods != 2 || w.CPURequest.String() != "2") {
+			t.Errorf("idle workload = %+v, want 2 pods requesting 2 CPUs", w)
+		}
+	}
+}
+
+func TestMetricsClientRequired(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	if _, err := kube.PodMetrics(context.TODO(), "default", ""); err == nil {
+		t.Error("PodMetrics() expected error without a metrics client")
+	}
+}
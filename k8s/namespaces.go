@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	networkingv1ac "k8s.io/client-go/applyconfigurations/networking/v1"
+	rbacv1ac "k8s.io/client-go/applyconfigurations/rbac/v1"
+)
+
+// Name shared by the quota, limit range and network policy created for a namespace
+const namespaceDefaultsName = "tenant-defaults"
+
+// NamespaceSpec describes a tenant namespace and the policy objects provisioned with it
+type NamespaceSpec struct {
+	Name            string
+	Labels          map[string]string
+	Annotations     map[string]string
+	Quota           corev1.ResourceList // ResourceQuota hard limits, skipped when empty
+	DefaultLimits   corev1.ResourceList // Container default limits in the LimitRange
+	DefaultRequests corev1.ResourceList // Container default requests in the LimitRange
+	IsolateNetwork  bool                // Only allow ingress from pods in the same namespace
+	ServiceAccount  string              // ServiceAccount to create, skipped when empty
+	RoleBindings    []RoleBindingSpec
+}
+
+// RoleBindingSpec binds a Role or ClusterRole inside the namespace, subjects default to the spec ServiceAccount
+type RoleBindingSpec struct {
+	Name     string
+	RoleKind string // Role or ClusterRole, defaults to ClusterRole
+	RoleName string
+	Subjects []rbacv1.Subject
+}
+
+// ProvisionNamespace creates or updates a namespace and its policy objects with server-side apply, so it is safe to rerun
+func (k *KubeClient) ProvisionNamespace(ctx context.Context, spec NamespaceSpec) error {
+	applyOpts := metav1.ApplyOptions{FieldManager: DefaultFieldManager, Force: true}
+	name := spec.Name
+
+	namespace := corev1ac.Namespace(name).WithLabels(spec.Labels).WithAnnotations(spec.Annotations)
+	if _, err := k.Clientset.CoreV1().Namespaces().Apply(ctx, namespace, applyOpts); err != nil {
+		return fmt.Errorf("kube: unable to apply namespace %s: %w", name, err)
+	}
+
+	if len(spec.Quota) > 0 {
+		quota := corev1ac.ResourceQuota(namespaceDefaultsName, name).
+			WithSpec(corev1ac.ResourceQuotaSpec().WithHard(spec.Quota))
+		if _, err := k.Clientset.CoreV1().ResourceQuotas(name).Apply(ctx, quota, applyOpts); err != nil {
+			return fmt.Errorf("kube: unable to apply resource quota in %s: %w", name, err)
+		}
+	}
+
+	if len(spec.DefaultLimits) > 0 || len(spec.DefaultRequests) > 0 {
+		item := corev1ac.LimitRangeItem().WithType(corev1.LimitTypeContainer)
+		if len(spec.DefaultLimits) > 0 {
+			item.WithDefault(spec.DefaultLimits)
+		}
+		if len(spec.DefaultRequests) > 0 {
+			item.WithDefaultRequest(spec.DefaultRequests)
+		}
+		limitRange := corev1ac.LimitRange(namespaceDefaultsName, name).
+			WithSpec(corev1ac.LimitRangeSpec().WithLimits(item))
+		if _, err := k.Clientset.CoreV1().LimitRanges(name).Apply(ctx, limitRange, applyOpts); err != nil {
+			return fmt.Errorf("kube: unable to apply limit range in %s: %w", name, err)
+		}
+	}
+
+	if spec.IsolateNetwork {
+		policy := networkingv1ac.NetworkPolicy(namespaceDefaultsName, name).
+			WithSpec(networkingv1ac.NetworkPolicySpec().
+				WithPodSelector(metav1ac.LabelSelector()).
+				WithPolicyTypes(networkingv1.PolicyTypeIngress).
+				WithIngress(networkingv1ac.NetworkPolicyIngressRule().
+					WithFrom(networkingv1ac.NetworkPolicyPeer().WithPodSelector(metav1ac.LabelSelector()))))
+		if _, err := k.Clientset.NetworkingV1().NetworkPolicies(name).Apply(ctx, policy, applyOpts); err != nil {
+			return fmt.Errorf("kube: unable to apply network policy in %s: %w", name, err)
+		}
+	}
+
+	if spec.ServiceAccount != "" {
+		serviceAccount := corev1ac.ServiceAccount(spec.ServiceAccount, name)
+		if _, err := k.Clientset.CoreV1().ServiceAccounts(name).Apply(ctx, serviceAccount, applyOpts); err != nil {
+			return fmt.Errorf("kube: unable to apply service account %s: %w", spec.ServiceAccount, err)
+		}
+	}
+
+	for _, binding := range spec.RoleBindings {
+		roleBinding, err := roleBindingApplyConfiguration(name, spec.ServiceAccount, binding)
+		if err != nil {
+			return err
+		}
+		if _, err := k.Clientset.RbacV1().RoleBindings(name).Apply(ctx, roleBinding, applyOpts); err != nil {
+			return fmt.Errorf("kube: unable to apply role binding %s: %w", binding.Name, err)
+		}
+	}
+	slog.Info("kube: namespace provisioned", slog.String("Namespace", name))
+	return nil
+}
+
+// DeprovisionNamespace deletes a namespace and blocks until its finalizers have run and it is gone
+func (k *KubeClient) DeprovisionNamespace(ctx context.Context, name string) error {
+	err := k.Clientset.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("kube: unable to delete namespace %s: %w", name, err)
+	}
+	err = wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		_, err := k.Clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("kube: namespace %s was not finalized: %w", name, err)
+	}
+	slog.Info("kube: namespace deprovisioned", slog.String("Namespace", name))
+	return nil
+}
+
+func roleBindingApplyConfiguration(namespace, serviceAccount string, binding RoleBindingSpec) (*rbacv1ac.RoleBindingApplyConfiguration, error) {
+	roleKind := binding.RoleKind
+	if roleKind == "" {
+		roleKind = "ClusterRole"
+	}
+	subjects := binding.Subjects
+	if len(subjects) == 0 {
+		if serviceAccount == "" {
+			return nil, fmt.Errorf("kube: role binding %s has no subjects and no service account to default to", binding.Name)
+		}
+		subjects = []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: namespace}}
+	}
+
+	roleBinding := rbacv1ac.RoleBinding(binding.Name, namespace).
+		WithRoleRef(rbacv1ac.RoleRef().
+			WithAPIGroup(rbacv1.GroupName).
+			WithKind(roleKind).
+			WithName(binding.RoleName))
+	for _, subject := range subjects {
+		s := rbacv1ac.Subject().WithKind(subject.Kind).WithName(subject.Name)
+		if subject.APIGroup != "" {
+			s.WithAPIGroup(subject.APIGroup)
+		}
+		if subject.Namespace != "" {
+			s.WithNamespace(subject.Namespace)
+		}
+		roleBinding.WithSubjects(s)
+	}
+	return roleBinding, nil
+}
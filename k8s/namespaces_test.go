@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestProvisionNamespace(t *testing.T) {
+	spec := NamespaceSpec{
+		Name:            "tenant-a",
+		Labels:          map[string]string{"tenant": "a"},
+		Quota:           corev1.ResourceList{corev1.ResourcePods: resource.MustParse("20")},
+		DefaultLimits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		DefaultRequests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		IsolateNetwork:  true,
+		ServiceAccount:  "deployer",
+		RoleBindings:    []RoleBindingSpec{{Name: "deployer-edit", RoleName: "edit"}},
+	}
+	clientset := fake.NewClientset()
+	kube := &KubeClient{Clientset: clientset}
+
+	// Provisioning twice must succeed and converge on the same objects
+	for i := 0; i < 2; i++ {
+		if err := kube.ProvisionNamespace(context.TODO(), spec); err != nil {
+			t.Fatalf("ProvisionNamespace() run %d error = %v", i+1, err)
+		}
+	}
+
+	ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), "tenant-a", metav1.GetOptions{})
+	if err != nil || ns.Labels["tenant"] != "a" {
+		t.Errorf("namespace = %v, %v, want tenant label", ns, err)
+	}
+	quota, err := clientset.CoreV1().ResourceQuotas("tenant-a").Get(context.TODO(), namespaceDefaultsName, metav1.GetOptions{})
+	if err != nil || !quota.Spec.Hard.Pods().Equal(resource.MustParse("20")) {
+		t.Errorf("resource quota = %v, %v, want 20 pods", quota, err)
+	}
+	if _, err := clientset.CoreV1().LimitRanges("tenant-a").Get(context.TODO(), namespaceDefaultsName, metav1.GetOptions{}); err != nil {
+		t.Errorf("limit range error = %v", err)
+	}
+	if _, err := clientset.NetworkingV1().NetworkPolicies("tenant-a").Get(context.TODO(), namespaceDefaultsName, metav1.GetOptions{}); err != nil {
+		t.Errorf("network policy error = %v", err)
+	}
+	binding, err := clientset.RbacV1().RoleBindings("tenant-a").Get(context.TODO(), "deployer-edit", metav1.GetOptions{})
+	if err != nil || len(binding.Subjects) != 1 || binding.Subjects[0].Name != "deployer" || binding.RoleRef.Kind != "ClusterRole" {
+		t.Errorf("role binding = %v, %v, want ClusterRole edit bound to deployer", binding, err)
+	}
+}
+
+func TestProvisionNamespaceBindingWithoutSubjects(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewClientset()}
+	spec := NamespaceSpec{Name: "tenant-b", RoleBindings: []RoleBindingSpec{{Name: "view", RoleName: "view"}}}
+	if err := kube.ProvisionNamespace(context.TODO(), spec); err == nil {
+		t.Error("ProvisionNamespace() expected error for binding without subjects")
+	}
+}
+
+func TestDeprovisionNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{name: "existing namespace", existing: true},
+		{name: "missing namespace", existing: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset()
+			if tt.existing {
+				_, _ = clientset.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}}, metav1.CreateOptions{})
+			}
+			kube := &KubeClient{Clientset: clientset}
+			if err := kube.DeprovisionNamespace(context.TODO(), "tenant-a"); err != nil {
+				t.Errorf("DeprovisionNamespace() error = %v", err)
+			}
+		})
+	}
+}
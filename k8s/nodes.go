@@ -0,0 +1,201 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Annotation set by the kubelet on mirror pods of static pods, which can't be evicted
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// DrainOptions controls how pods are evicted from a node
+type DrainOptions struct {
+	DeleteEmptyDirData bool          // Evict pods using emptyDir volumes, losing their data
+	Force              bool          // Evict pods that aren't managed by a controller
+	GracePeriodSeconds *int64        // Overrides the pod termination grace period
+	PodTimeout         time.Duration // How long to keep retrying and waiting for each pod, defaults to 5 minutes
+	RetryInterval      time.Duration // Delay between evictions refused by a PodDisruptionBudget, defaults to 5 seconds
+}
+
+// DrainResult lists what happened to every pod on the drained node
+type DrainResult struct {
+	Evicted []string     `json:"evicted"`
+	Skipped []string     `json:"skipped"` // DaemonSet and mirror pods
+	Blocked []BlockedPod `json:"blocked"`
+}
+
+// BlockedPod is a pod that prevented the drain from completing
+type BlockedPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+// CordonNode marks the node unschedulable
+func (k *KubeClient) CordonNode(ctx context.Context, name string) error {
+	return k.setNodeUnschedulable(ctx, name, true)
+}
+
+// UncordonNode marks the node schedulable again
+func (k *KubeClient) UncordonNode(ctx context.Context, name string) error {
+	return k.setNodeUnschedulable(ctx, name, false)
+}
+
+func (k *KubeClient) setNodeUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	patchData := fmt.Sprintf(`{"spec": {"unschedulable": %t}}`, unschedulable)
+	_, err := k.Clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, []byte(patchData), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("kube: unable to set unschedulable=%t on node %s: %w", unschedulable, name, err)
+	}
+	return nil
+}
+
+// DrainNode cordons the node and evicts its pods through the eviction API so PodDisruptionBudgets are honoured.
+// DaemonSet and mirror pods are skipped. An error is returned together with the result when any pod blocked the drain.
+func (k *KubeClient) DrainNode(ctx context.Context, name string, opts DrainOptions) (*DrainResult, error) {
+	if opts.PodTimeout <= 0 {
+		opts.PodTimeout = 5 * time.Minute
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if err := k.CordonNode(ctx, name); err != nil {
+		return nil, err
+	}
+
+	pods, _, err := k.ListPods(ctx, metav1.NamespaceAll, ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+		All:           true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DrainResult{}
+	var toEvict []corev1.Pod
+	for _, pod := range pods {
+		if pod.Spec.NodeName != name {
+			continue
+		}
+		ref := pod.Namespace + "/" + pod.Name
+		switch reason := drainFilter(&pod, opts); reason {
+		case "":
+			toEvict = append(toEvict, pod)
+		case drainSkip:
+			result.Skipped = append(result.Skipped, ref)
+		default:
+			result.Blocked = append(result.Blocked, BlockedPod{Namespace: pod.Namespace, Name: pod.Name, Reason: reason})
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, pod := range toEvict {
+		wg.Add(1)
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+			err := k.evictPod(ctx, &pod, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Blocked = append(result.Blocked, BlockedPod{Namespace: pod.Namespace, Name: pod.Name, Reason: err.Error()})
+				return
+			}
+			result.Evicted = append(result.Evicted, pod.Namespace+"/"+pod.Name)
+		}(pod)
+	}
+	wg.Wait()
+
+	if len(result.Blocked) > 0 {
+		return result, fmt.Errorf("kube: drain of node %s blocked by %d pods", name, len(result.Blocked))
+	}
+	slog.Info("kube: node drained", slog.String("Node", name), slog.Int("Evicted", len(result.Evicted)))
+	return result, nil
+}
+
+const drainSkip = "skip"
+
+// drainFilter returns "" for pods to evict, drainSkip for pods to leave alone, or why the pod blocks the drain
+func drainFilter(pod *corev1.Pod, opts DrainOptions) string {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return drainSkip
+	}
+	controller := metav1.GetControllerOf(pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		return drainSkip
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return ""
+	}
+	if controller == nil && !opts.Force {
+		return "pod is not managed by a controller, use Force to evict it"
+	}
+	if !opts.DeleteEmptyDirData {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				return "pod uses emptyDir volume " + volume.Name + ", use DeleteEmptyDirData to evict it"
+			}
+		}
+	}
+	return ""
+}
+
+// evictPod retries the eviction while a disruption budget refuses it, then waits for the pod to go away
+func (k *KubeClient) evictPod(ctx context.Context, pod *corev1.Pod, opts DrainOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.PodTimeout)
+	defer cancel()
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	}
+	if opts.GracePeriodSeconds != nil {
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: opts.GracePeriodSeconds}
+	}
+
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, opts.RetryInterval, true, func(ctx context.Context) (bool, error) {
+		lastErr = k.Clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case lastErr == nil, apierrors.IsNotFound(lastErr):
+			return true, nil
+		case apierrors.IsTooManyRequests(lastErr):
+			// Refused by a PodDisruptionBudget, try again later
+			return false, nil
+		default:
+			return false, lastErr
+		}
+	})
+	if err != nil {
+		if lastErr != nil {
+			return fmt.Errorf("eviction failed: %v", lastErr)
+		}
+		return fmt.Errorf("eviction failed: %v", err)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		current, err := k.Clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// A controller may have recreated a pod with the same name
+		return current.UID != pod.UID, nil
+	})
+	if err != nil {
+		return fmt.Errorf("pod was not deleted in time: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newNodePod(name, ownerKind string, volumes ...corev1.Volume) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: "node-a", Volumes: volumes},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if ownerKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: &controller}}
+	}
+	return pod
+}
+
+func TestCordonUncordonNode(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}})
+	kube := &KubeClient{Clientset: clientset}
+
+	if err := kube.CordonNode(context.TODO(), "node-a"); err != nil {
+		t.Fatalf("CordonNode() error = %v", err)
+	}
+	node, _ := clientset.CoreV1().Nodes().Get(context.TODO(), "node-a", metav1.GetOptions{})
+	if !node.Spec.Unschedulable {
+		t.Error("CordonNode() node is schedulable")
+	}
+	if err := kube.UncordonNode(context.TODO(), "node-a"); err != nil {
+		t.Fatalf("UncordonNode() error = %v", err)
+	}
+	node, _ = clientset.CoreV1().Nodes().Get(context.TODO(), "node-a", metav1.GetOptions{})
+	if node.Spec.Unschedulable {
+		t.Error("UncordonNode() node is unschedulable")
+	}
+	if err := kube.CordonNode(context.TODO(), "missing"); err == nil {
+		t.Error("CordonNode() expected error for missing node")
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	emptyDir := corev1.Volume{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	otherNode := newNodePod("elsewhere", "ReplicaSet")
+	otherNode.Spec.NodeName = "node-b"
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		newNodePod("web", "ReplicaSet"),
+		newNodePod("agent", "DaemonSet"),
+		newNodePod("bare", ""),
+		newNodePod("cache", "ReplicaSet", emptyDir),
+		newNodePod("guarded", "StatefulSet"),
+		otherNode,
+	)
+	podsResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		if name == "guarded" {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 1)
+		}
+		return true, nil, clientset.Tracker().Delete(podsResource, "default", name)
+	})
+	kube := &KubeClient{Clientset: clientset}
+
+	result, err := kube.DrainNode(context.TODO(), "node-a", DrainOptions{
+		PodTimeout:    300 * time.Millisecond,
+		RetryInterval: 50 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("DrainNode() expected error for blocked pods")
+	}
+	if len(result.Evicted) != 1 || result.Evicted[0] != "default/web" {
+		t.Errorf("Evicted = %v, want [default/web]", result.Evicted)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "default/agent" {
+		t.Errorf("Skipped = %v, want [default/agent]", result.Skipped)
+	}
+	var blocked []string
+	for _, pod := range result.Blocked {
+		blocked = append(blocked, pod.Name)
+	}
+	sort.Strings(blocked)
+	if len(blocked) != 3 || blocked[0] != "bare" || blocked[1] != "cache" || blocked[2] != "guarded" {
+		t.Errorf("Blocked = %v, want [bare cache guarded]", blocked)
+	}
+	node, _ := clientset.CoreV1().Nodes().Get(context.TODO(), "node-a", metav1.GetOptions{})
+	if !node.Spec.Unschedulable {
+		t.Error("DrainNode() did not cordon the node")
+	}
+}
+
+func TestDrainNodeWithOverrides(t *testing.T) {
+	emptyDir := corev1.Volume{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		newNodePod("bare", ""),
+		newNodePod("cache", "ReplicaSet", emptyDir),
+	)
+	podsResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		return true, nil, clientset.Tracker().Delete(podsResource, "default", name)
+	})
+	kube := &KubeClient{Clientset: clientset}
+
+	result, err := kube.DrainNode(context.TODO(), "node-a", DrainOptions{Force: true, DeleteEmptyDirData: true})
+	if err != nil {
+		t.Fatalf("DrainNode() error = %v", err)
+	}
+	if len(result.Evicted) != 2 {
+		t.Errorf("Evicted = %v, want both pods", result.Evicted)
+	}
+}
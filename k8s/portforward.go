@@ -0,0 +1,188 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForwardHandle is a running port forward, call Stop to release the local port
+type PortForwardHandle struct {
+	LocalAddr string // Bound local address, e.g. 127.0.0.1:54321
+	Pod       string // Pod the traffic is forwarded to
+
+	stopCh   chan struct{}
+	done     chan error
+	finished chan struct{} // Closed after done, can be waited on without taking the error
+	stopOnce sync.Once
+}
+
+// Stop closes the listener and all forwarded connections
+func (h *PortForwardHandle) Stop() {
+	h.stopOnce.Do(func() { close(h.stopCh) })
+}
+
+// Done is closed once forwarding has ended, it yields the error that ended it if any
+func (h *PortForwardHandle) Done() <-chan error {
+	return h.done
+}
+
+// PortForward forwards localPort on 127.0.0.1 to remotePort of a pod or service.
+// ref is "pod/name", "svc/name", "service/name" or a bare pod name. For a service, remotePort is the
+// service port and is mapped to the target port of a ready backing pod. A localPort of 0 picks a free port.
+func (k *KubeClient) PortForward(ctx context.Context, namespace, ref string, localPort, remotePort int) (*PortForwardHandle, error) {
+	if k.Config == nil {
+		return nil, fmt.Errorf("kube: rest config is not initialized")
+	}
+	podName, podPort, err := k.resolvePortForwardTarget(ctx, namespace, ref, remotePort)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(k.Config)
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to create port forward transport: %w", err)
+	}
+	url := k.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	handle := &PortForwardHandle{
+		Pod:      podName,
+		stopCh:   make(chan struct{}),
+		done:     make(chan error, 1),
+		finished: make(chan struct{}),
+	}
+	readyCh := make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", localPort, podPort)}
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, ports, handle.stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to create port forward to %s: %w", podName, err)
+	}
+
+	go func() {
+		err := forwarder.ForwardPorts()
+		if err != nil {
+			handle.done <- err
+		}
+		close(handle.done)
+		close(handle.finished)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			handle.Stop()
+		case <-handle.stopCh:
+		case <-handle.finished:
+		}
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-handle.done:
+		handle.Stop()
+		if err == nil {
+			return nil, fmt.Errorf("kube: port forward to %s ended before ready", podName)
+		}
+		return nil, fmt.Errorf("kube: port forward to %s failed: %w", podName, err)
+	case <-ctx.Done():
+		handle.Stop()
+		return nil, ctx.Err()
+	}
+
+	forwarded, err := forwarder.GetPorts()
+	if err != nil || len(forwarded) == 0 {
+		handle.Stop()
+		return nil, fmt.Errorf("kube: unable to determine forwarded port for %s: %v", podName, err)
+	}
+	handle.LocalAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(int(forwarded[0].Local)))
+	slog.Info("kube: port forward ready", slog.String("LocalAddr", handle.LocalAddr), slog.String("Pod", podName), slog.Int("RemotePort", podPort))
+	return handle, nil
+}
+
+// resolvePortForwardTarget turns a pod or service reference into a pod name and container port
+func (k *KubeClient) resolvePortForwardTarget(ctx context.Context, namespace, ref string, remotePort int) (string, int, error) {
+	kind, name, found := strings.Cut(ref, "/")
+	if !found {
+		kind, name = "pod", ref
+	}
+	switch strings.ToLower(kind) {
+	case "pod", "pods", "po":
+		return name, remotePort, nil
+	case "service", "services", "svc":
+	default:
+		return "", 0, fmt.Errorf("kube: unsupported port forward target %q", ref)
+	}
+
+	service, err := k.Clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, fmt.Errorf("kube: unable to retrieve service %s: %w", name, err)
+	}
+	if len(service.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("kube: service %s has no selector", name)
+	}
+	pods, err := k.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(service.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("kube: unable to list pods for service %s: %w", name, err)
+	}
+	for _, pod := range pods.Items {
+		if !isPodReady(&pod) {
+			continue
+		}
+		return pod.Name, servicePortToPodPort(service, &pod, remotePort), nil
+	}
+	return "", 0, fmt.Errorf("kube: service %s has no ready pods", name)
+}
+
+// servicePortToPodPort maps a service port to its target port on the pod, falling back to the port itself
+func servicePortToPodPort(service *corev1.Service, pod *corev1.Pod, port int) int {
+	for _, servicePort := range service.Spec.Ports {
+		if int(servicePort.Port) != port {
+			continue
+		}
+		switch {
+		case servicePort.TargetPort.Type == intstr.Int && servicePort.TargetPort.IntVal != 0:
+			return int(servicePort.TargetPort.IntVal)
+		case servicePort.TargetPort.Type == intstr.String && servicePort.TargetPort.StrVal != "":
+			for _, container := range pod.Spec.Containers {
+				for _, containerPort := range container.Ports {
+					if containerPort.Name == servicePort.TargetPort.StrVal {
+						return int(containerPort.ContainerPort)
+					}
+				}
+			}
+		}
+	}
+	return port
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPod(name string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "postgres"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "postgres",
+			Ports: []corev1.ContainerPort{{Name: "pg", ContainerPort: 5432}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestResolvePortForwardTarget(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "postgres"},
+			Ports: []corev1.ServicePort{
+				{Port: 5432, TargetPort: intstr.FromString("pg")},
+				{Port: 9187, TargetPort: intstr.FromInt32(9100)},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		ref        string
+		remotePort int
+		objects    []runtime.Object
+		wantPod    string
+		wantPort   int
+		wantErr    bool
+	}{
+		{name: "bare pod name", ref: "web-1", remotePort: 8080, wantPod: "web-1", wantPort: 8080},
+		{name: "service with named target port", ref: "svc/postgres", remotePort: 5432, objects: []runtime.Object{service, newTestPod("pg-0", false), newTestPod("pg-1", true)}, wantPod: "pg-1", wantPort: 5432},
+		{name: "service with numeric target port", ref: "service/postgres", remotePort: 9187, objects: []runtime.Object{service, newTestPod("pg-1", true)}, wantPod: "pg-1", wantPort: 9100},
+		{name: "service without ready pods", ref: "svc/postgres", remotePort: 5432, objects: []runtime.Object{service, newTestPod("pg-0", false)}, wantErr: true},
+		{name: "unsupported kind", ref: "deployment/postgres", remotePort: 5432, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, obj := range tt.objects {
+				_ = clientset.Tracker().Add(obj)
+			}
+			kube := &KubeClient{Clientset: clientset}
+			pod, port, err := kube.resolvePortForwardTarget(context.TODO(), "default", tt.ref, tt.remotePort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolvePortForwardTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pod != tt.wantPod || port != tt.wantPort {
+				t.Errorf("resolvePortForwardTarget() = %s:%d, want %s:%d", pod, port, tt.wantPod, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestPortForwardWithoutConfig(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset()}
+	if _, err := kube.PortForward(context.TODO(), "default", "pod/web-1", 0, 8080); err == nil {
+		t.Error("PortForward() expected error without rest config")
+	}
+}
@@ -0,0 +1,234 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itsvictorfy/pkg/formatting"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Container waiting reasons reported as unhealthy pods
+var unhealthyWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+}
+
+// ClusterReport is a point-in-time inventory of cluster health problems
+type ClusterReport struct {
+	GeneratedAt            time.Time          `json:"generatedAt"`
+	ServerVersion          string             `json:"serverVersion"`
+	Nodes                  []NodeHealth       `json:"nodes"`
+	UnhealthyPods          []PodIssue         `json:"unhealthyPods"`
+	PendingPVCs            []ResourceRef      `json:"pendingPvcs"`
+	FailedJobs             []ResourceRef      `json:"failedJobs"`
+	UnavailableDeployments []DeploymentHealth `json:"unavailableDeployments"`
+}
+
+// NodeHealth is the readiness and pressure state of a node
+type NodeHealth struct {
+	Name          string   `json:"name"`
+	Ready         bool     `json:"ready"`
+	Unschedulable bool     `json:"unschedulable"`
+	Pressure      []string `json:"pressure,omitempty"` // Conditions such as MemoryPressure that are currently true
+}
+
+// PodIssue is a container stuck in a failing waiting state
+type PodIssue struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+	Reason    string `json:"reason"`
+	Restarts  int32  `json:"restarts"`
+}
+
+// ResourceRef points at a namespaced object with the reason it was reported
+type ResourceRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// DeploymentHealth is the replica state of a deployment that isn't fully available
+type DeploymentHealth struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Desired     int32  `json:"desired"`
+	Available   int32  `json:"available"`
+	Unavailable int32  `json:"unavailable"`
+}
+
+// ClusterReport collects node, pod, PVC, job and deployment health across all namespaces
+func (k *KubeClient) ClusterReport(ctx context.Context) (*ClusterReport, error) {
+	report := &ClusterReport{GeneratedAt: time.Now()}
+
+	version, err := k.Clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve server version: %w", err)
+	}
+	report.ServerVersion = version.GitVersion
+
+	nodes, err := k.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		report.Nodes = append(report.Nodes, nodeHealth(&node))
+	}
+
+	all := ListOptions{All: true}
+	pods, _, err := k.ListPods(ctx, metav1.NamespaceAll, all)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		report.UnhealthyPods = append(report.UnhealthyPods, podIssues(&pod)...)
+	}
+
+	pvcs, _, err := k.ListPVCs(ctx, metav1.NamespaceAll, all)
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		if pvc.Status.Phase == corev1.ClaimPending {
+			report.PendingPVCs = append(report.PendingPVCs, ResourceRef{Namespace: pvc.Namespace, Name: pvc.Name, Reason: string(pvc.Status.Phase)})
+		}
+	}
+
+	jobs, _, err := k.ListJobs(ctx, metav1.NamespaceAll, all)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if jobOutcome(&job) == JobFailed {
+			report.FailedJobs = append(report.FailedJobs, ResourceRef{Namespace: job.Namespace, Name: job.Name, Reason: jobFailureReason(job.Status.Conditions)})
+		}
+	}
+
+	deployments, _, err := k.ListDeployments(ctx, metav1.NamespaceAll, all)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		if d.Status.UnavailableReplicas > 0 || d.Status.AvailableReplicas < desired {
+			report.UnavailableDeployments = append(report.UnavailableDeployments, DeploymentHealth{
+				Namespace:   d.Namespace,
+				Name:        d.Name,
+				Desired:     desired,
+				Available:   d.Status.AvailableReplicas,
+				Unavailable: d.Status.UnavailableReplicas,
+			})
+		}
+	}
+	return report, nil
+}
+
+// Healthy reports whether every node is ready and no problems were found
+func (r *ClusterReport) Healthy() bool {
+	for _, node := range r.Nodes {
+		if !node.Ready || len(node.Pressure) > 0 {
+			return false
+		}
+	}
+	return len(r.UnhealthyPods) == 0 && len(r.PendingPVCs) == 0 && len(r.FailedJobs) == 0 && len(r.UnavailableDeployments) == 0
+}
+
+// Map flattens the report into the shape expected by formatting.MapToString
+func (r *ClusterReport) Map() map[string]interface{} {
+	nodes := make([]interface{}, 0, len(r.Nodes))
+	for _, node := range r.Nodes {
+		state := "Ready"
+		if !node.Ready {
+			state = "NotReady"
+		}
+		if node.Unschedulable {
+			state += ",SchedulingDisabled"
+		}
+		if len(node.Pressure) > 0 {
+			state += " " + strings.Join(node.Pressure, ",")
+		}
+		nodes = append(nodes, fmt.Sprintf("%s: %s", node.Name, state))
+	}
+	pods := make([]interface{}, 0, len(r.UnhealthyPods))
+	for _, pod := range r.UnhealthyPods {
+		pods = append(pods, fmt.Sprintf("%s/%s (%s): %s, %d restarts", pod.Namespace, pod.Name, pod.Container, pod.Reason, pod.Restarts))
+	}
+	deployments := make([]interface{}, 0, len(r.UnavailableDeployments))
+	for _, d := range r.UnavailableDeployments {
+		deployments = append(deployments, fmt.Sprintf("%s/%s: %d/%d available", d.Namespace, d.Name, d.Available, d.Desired))
+	}
+	return map[string]interface{}{
+		"generatedAt":            r.GeneratedAt.Format(time.RFC3339),
+		"serverVersion":          r.ServerVersion,
+		"healthy":                r.Healthy(),
+		"nodes":                  nodes,
+		"unhealthyPods":          pods,
+		"pendingPvcs":            resourceRefs(r.PendingPVCs),
+		"failedJobs":             resourceRefs(r.FailedJobs),
+		"unavailableDeployments": deployments,
+	}
+}
+
+func (r *ClusterReport) String() string {
+	return formatting.MapToString(r.Map())
+}
+
+func nodeHealth(node *corev1.Node) NodeHealth {
+	health := NodeHealth{Name: node.Name, Unschedulable: node.Spec.Unschedulable}
+	for _, condition := range node.Status.Conditions {
+		switch condition.Type {
+		case corev1.NodeReady:
+			health.Ready = condition.Status == corev1.ConditionTrue
+		case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure, corev1.NodeNetworkUnavailable:
+			if condition.Status == corev1.ConditionTrue {
+				health.Pressure = append(health.Pressure, string(condition.Type))
+			}
+		}
+	}
+	return health
+}
+
+func podIssues(pod *corev1.Pod) []PodIssue {
+	var issues []PodIssue
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting == nil || !unhealthyWaitingReasons[status.State.Waiting.Reason] {
+			continue
+		}
+		issues = append(issues, PodIssue{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Container: status.Name,
+			Reason:    status.State.Waiting.Reason,
+			Restarts:  status.RestartCount,
+		})
+	}
+	return issues
+}
+
+func jobFailureReason(conditions []batchv1.JobCondition) string {
+	for _, condition := range conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue && condition.Reason != "" {
+			return condition.Reason
+		}
+	}
+	return string(JobFailed)
+}
+
+func resourceRefs(refs []ResourceRef) []interface{} {
+	items := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		items = append(items, fmt.Sprintf("%s/%s: %s", ref.Namespace, ref.Name, ref.Reason))
+	}
+	return items
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterReport(t *testing.T) {
+	replicas := int32(2)
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+			}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "apps"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "web",
+				RestartCount: 7,
+				State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "healthy", Namespace: "apps"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "web",
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}}},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "db"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "db"},
+			Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+			}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 1, UnavailableReplicas: 1},
+		},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.32.3"}
+	kube := &KubeClient{Clientset: clientset}
+
+	report, err := kube.ClusterReport(context.TODO())
+	if err != nil {
+		t.Fatalf("ClusterReport() error = %v", err)
+	}
+	if report.ServerVersion != "v1.32.3" {
+		t.Errorf("ServerVersion = %v, want v1.32.3", report.ServerVersion)
+	}
+	if len(report.Nodes) != 1 || !report.Nodes[0].Ready || len(report.Nodes[0].Pressure) != 1 {
+		t.Errorf("Nodes = %+v, want one ready node with disk pressure", report.Nodes)
+	}
+	if len(report.UnhealthyPods) != 1 || report.UnhealthyPods[0].Reason != "CrashLoopBackOff" {
+		t.Errorf("UnhealthyPods = %+v, want web-1 in CrashLoopBackOff", report.UnhealthyPods)
+	}
+	if len(report.PendingPVCs) != 1 || len(report.FailedJobs) != 1 || report.FailedJobs[0].Reason != "BackoffLimitExceeded" {
+		t.Errorf("PendingPVCs = %+v, FailedJobs = %+v", report.PendingPVCs, report.FailedJobs)
+	}
+	if len(report.UnavailableDeployments) != 1 || report.UnavailableDeployments[0].Unavailable != 1 {
+		t.Errorf("UnavailableDeployments = %+v, want api with one unavailable replica", report.UnavailableDeployments)
+	}
+	if report.Healthy() {
+		t.Error("Healthy() = true, want false")
+	}
+	if out := report.String(); !strings.Contains(out, "apps/web-1 (web): CrashLoopBackOff, 7 restarts") {
+		t.Errorf("String() = %q, missing pod issue", out)
+	}
+}
@@ -0,0 +1,344 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// RevisionAnnotation is set by the deployment controller on deployments and their ReplicaSets
+	RevisionAnnotation = "deployment.kubernetes.io/revision"
+	// ChangeCauseAnnotation records why a revision was created, shown by DeploymentHistory
+	ChangeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// ImageUpdateOptions controls how UpdateDeploymentImage verifies the new image
+type ImageUpdateOptions struct {
+	RolloutTimeout  time.Duration // How long the rollout may take, defaults to five minutes
+	SoakPeriod      time.Duration // How long pods must stay ready after the rollout, 0 skips the soak
+	MaxRestarts     int32         // Container restarts tolerated during the soak
+	PollInterval    time.Duration // Defaults to two seconds
+	DisableRollback bool          // Leave a failed rollout in place instead of undoing it
+}
+
+// ImageUpdateResult describes the outcome of UpdateDeploymentImage
+type ImageUpdateResult struct {
+	PreviousImage string
+	RolledBack    bool
+	RolledBackTo  int64 // Revision restored by the rollback
+	Reason        string
+}
+
+// DeploymentRevision is one entry of a deployment's rollout history
+type DeploymentRevision struct {
+	Revision    int64
+	ReplicaSet  string
+	Images      map[string]string // Container name to image
+	ChangeCause string
+	CreatedAt   time.Time
+	Current     bool
+}
+
+// UpdateDeploymentImage sets the image of one container, waits for the rollout and watches the pods for the soak period.
+// When the rollout stalls or pods become unready or restart too often, the deployment is rolled back to the revision
+// that ran before the update and the returned error explains why.
+func (k *KubeClient) UpdateDeploymentImage(ctx context.Context, name, namespace, container, image string, opts ImageUpdateOptions) (*ImageUpdateResult, error) {
+	if opts.RolloutTimeout <= 0 {
+		opts.RolloutTimeout = 5 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+
+	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve deployment %s: %w", name, err)
+	}
+	result := &ImageUpdateResult{}
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		if c.Name == container {
+			result.PreviousImage = c.Image
+		}
+	}
+	if result.PreviousImage == "" {
+		return nil, fmt.Errorf("kube: deployment %s has no container %s", name, container)
+	}
+	// Roll back to the revision running before the update, which stays right even if the controller never bumped it
+	previousRevision, _ := strconv.ParseInt(deployment.Annotations[RevisionAnnotation], 10, 64)
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}},"spec":{"template":{"spec":{"containers":[{"name":%q,"image":%q}]}}}}`,
+		ChangeCauseAnnotation, fmt.Sprintf("image %s set to %s", container, image), container, image)
+	_, err = k.Clientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to update image of deployment %s: %w", name, err)
+	}
+	slog.Info("kube: deployment image updated", slog.String("Deployment", name), slog.String("Container", container), slog.String("Image", image))
+
+	failure := k.waitForRollout(ctx, name, namespace, opts)
+	if failure == "" && opts.SoakPeriod > 0 {
+		failure = k.soakDeployment(ctx, name, namespace, opts)
+	}
+	if failure == "" {
+		return result, nil
+	}
+
+	result.Reason = failure
+	if opts.DisableRollback {
+		return result, fmt.Errorf("kube: rollout of %s failed: %s", name, failure)
+	}
+	slog.Warn("kube: rollout failed, rolling back", slog.String("Deployment", name), slog.String("Reason", failure))
+	revision, err := k.RollbackDeployment(ctx, name, namespace, previousRevision)
+	if err != nil {
+		return result, fmt.Errorf("kube: rollout of %s failed (%s) and rollback failed: %w", name, failure, err)
+	}
+	result.RolledBack = true
+	result.RolledBackTo = revision
+	return result, fmt.Errorf("kube: rollout of %s failed and was rolled back to revision %d: %s", name, revision, failure)
+}
+
+// waitForRollout polls until every replica runs the new template, returning why it did not
+func (k *KubeClient) waitForRollout(ctx context.Context, name, namespace string, opts ImageUpdateOptions) string {
+	var reason string
+	err := wait.PollUntilContextTimeout(ctx, opts.PollInterval, opts.RolloutTimeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		done, msg := rolloutComplete(deployment)
+		reason = msg
+		if msg == "progress deadline exceeded" {
+			return false, fmt.Errorf("%s", msg)
+		}
+		return done, nil
+	})
+	if err == nil {
+		return ""
+	}
+	if reason == "" {
+		reason = err.Error()
+	}
+	return reason
+}
+
+// rolloutComplete mirrors kubectl rollout status, returning what the rollout is still waiting for
+func rolloutComplete(deployment *appsv1.Deployment) (bool, string) {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, "waiting for the controller to observe the update"
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, "progress deadline exceeded"
+		}
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas)
+	}
+	return true, ""
+}
+
+// soakDeployment watches the pods of the new ReplicaSet for the soak period, returning why they were not healthy.
+// Pods of older ReplicaSets may still be terminating and are left out. The soak only passes when the period runs
+// out with pods to check, not when ctx is cancelled first.
+func (k *KubeClient) soakDeployment(ctx context.Context, name, namespace string, opts ImageUpdateOptions) string {
+	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err.Error()
+	}
+	selector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	replicaSets, err := k.deploymentReplicaSets(ctx, deployment)
+	if err != nil {
+		return err.Error()
+	}
+	for _, rs := range replicaSets {
+		hash := rs.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		if rs.Annotations[RevisionAnnotation] == deployment.Annotations[RevisionAnnotation] && hash != "" {
+			selector += "," + appsv1.DefaultDeploymentUniqueLabelKey + "=" + hash
+		}
+	}
+	baseline := map[types.UID]int32{}
+	first := true
+
+	var reason string
+	soakCtx, cancel := context.WithTimeout(ctx, opts.SoakPeriod)
+	defer cancel()
+	_ = wait.PollUntilContextCancel(soakCtx, opts.PollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, _, err := k.ListPods(ctx, namespace, ListOptions{LabelSelector: selector, All: true})
+		if err != nil {
+			// Transient API errors shouldn't fail the soak, unless the pods could never be checked
+			reason = fmt.Sprintf("unable to list pods: %v", err)
+			return false, nil
+		}
+		var restarts, live int32
+		for i := range pods {
+			pod := &pods[i]
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			live++
+			count := podRestarts(pod)
+			if first {
+				baseline[pod.UID] = count
+			}
+			restarts += count - baseline[pod.UID]
+			if !isPodReady(pod) {
+				reason = fmt.Sprintf("pod %s is not ready", pod.Name)
+				return true, nil
+			}
+		}
+		first = false
+		if live == 0 {
+			reason = "no pods of the new revision found"
+			return false, nil
+		}
+		reason = ""
+		if restarts > opts.MaxRestarts {
+			reason = fmt.Sprintf("%d container restarts during soak, %d allowed", restarts, opts.MaxRestarts)
+			return true, nil
+		}
+		return false, nil
+	})
+	if reason == "" && ctx.Err() != nil {
+		reason = fmt.Sprintf("soak interrupted: %v", ctx.Err())
+	}
+	return reason
+}
+
+func podRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return restarts
+}
+
+// DeploymentHistory lists the revisions kept in the deployment's ReplicaSets, oldest first
+func (k *KubeClient) DeploymentHistory(ctx context.Context, name, namespace string) ([]DeploymentRevision, error) {
+	deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to retrieve deployment %s: %w", name, err)
+	}
+	replicaSets, err := k.deploymentReplicaSets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	current := deployment.Annotations[RevisionAnnotation]
+
+	history := make([]DeploymentRevision, 0, len(replicaSets))
+	for _, rs := range replicaSets {
+		images := map[string]string{}
+		for _, c := range rs.Spec.Template.Spec.Containers {
+			images[c.Name] = c.Image
+		}
+		history = append(history, DeploymentRevision{
+			Revision:    replicaSetRevision(&rs),
+			ReplicaSet:  rs.Name,
+			Images:      images,
+			ChangeCause: rs.Annotations[ChangeCauseAnnotation],
+			CreatedAt:   rs.CreationTimestamp.Time,
+			Current:     rs.Annotations[RevisionAnnotation] == current,
+		})
+	}
+	return history, nil
+}
+
+// RollbackDeployment restores the pod template of a previous revision, like kubectl rollout undo.
+// A revision of 0 selects the one before the current revision. It returns the revision restored.
+func (k *KubeClient) RollbackDeployment(ctx context.Context, name, namespace string, revision int64) (int64, error) {
+	var restored int64
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := k.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		replicaSets, err := k.deploymentReplicaSets(ctx, deployment)
+		if err != nil {
+			return err
+		}
+		current, _ := strconv.ParseInt(deployment.Annotations[RevisionAnnotation], 10, 64)
+
+		var target *appsv1.ReplicaSet
+		for i := range replicaSets {
+			rs := &replicaSets[i]
+			rev := replicaSetRevision(rs)
+			if revision > 0 && rev == revision {
+				target = rs
+			}
+			// replicaSets is sorted, so the last one below the current revision is the previous one
+			if revision == 0 && rev < current {
+				target = rs
+			}
+		}
+		if target == nil {
+			if revision > 0 {
+				return fmt.Errorf("revision %d not found", revision)
+			}
+			return fmt.Errorf("no revision before %d", current)
+		}
+
+		template := target.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		deployment.Spec.Template = *template
+		if cause, ok := target.Annotations[ChangeCauseAnnotation]; ok {
+			if deployment.Annotations == nil {
+				deployment.Annotations = map[string]string{}
+			}
+			deployment.Annotations[ChangeCauseAnnotation] = cause
+		}
+		if _, err := k.Clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		restored = replicaSetRevision(target)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("kube: unable to roll back deployment %s: %w", name, err)
+	}
+	slog.Info("kube: deployment rolled back", slog.String("Deployment", name), slog.Int64("Revision", restored))
+	return restored, nil
+}
+
+// deploymentReplicaSets returns the ReplicaSets controlled by the deployment, sorted by revision
+func (k *KubeClient) deploymentReplicaSets(ctx context.Context, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	list, err := k.Clientset.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kube: unable to list replica sets of %s: %w", deployment.Name, err)
+	}
+	var owned []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deployment.UID {
+			owned = append(owned, rs)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return replicaSetRevision(&owned[i]) < replicaSetRevision(&owned[j])
+	})
+	return owned, nil
+}
+
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[RevisionAnnotation], 10, 64)
+	return revision
+}
@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newRolloutObjects returns a deployment at revision 2 that reports a finished rollout, its two ReplicaSets and one pod
+// of the revision 2 ReplicaSet
+func newRolloutObjects(podReady bool) []runtime.Object {
+	replicas := int32(1)
+	labels := map[string]string{"app": "web"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", UID: "web-uid",
+			Annotations: map[string]string{RevisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "web:v2"}}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	controller := true
+	replicaSet := func(revision, image, cause string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "web-" + revision, Namespace: "default", Labels: labels,
+				Annotations:     map[string]string{RevisionAnnotation: revision, ChangeCauseAnnotation: cause},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "web-uid", Controller: &controller}},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "hash-" + revision}},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+				},
+			},
+		}
+	}
+	pod := newTestPod("web-abc", podReady)
+	pod.Labels = map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "hash-2"}
+	pod.UID = "pod-uid"
+	return []runtime.Object{deployment, replicaSet("1", "web:v1", "initial"), replicaSet("2", "web:v2", "bump"), pod}
+}
+
+func TestDeploymentHistory(t *testing.T) {
+	kube := &KubeClient{Clientset: fake.NewSimpleClientset(newRolloutObjects(true)...)}
+
+	history, err := kube.DeploymentHistory(context.TODO(), "web", "default")
+	if err != nil {
+		t.Fatalf("DeploymentHistory() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("DeploymentHistory() returned %d revisions, want 2", len(history))
+	}
+	if history[0].Revision != 1 || history[0].Images["app"] != "web:v1" || history[0].ChangeCause != "initial" || history[0].Current {
+		t.Errorf("revision 1 = %+v", history[0])
+	}
+	if history[1].Revision != 2 || !history[1].Current {
+		t.Errorf("revision 2 = %+v, want current", history[1])
+	}
+}
+
+func TestRollbackDeployment(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newRolloutObjects(true)...)
+	kube := &KubeClient{Clientset: clientset}
+
+	revision, err := kube.RollbackDeployment(context.TODO(), "web", "default", 0)
+	if err != nil {
+		t.Fatalf("RollbackDeployment() error = %v", err)
+	}
+	if revision != 1 {
+		t.Errorf("RollbackDeployment() restored revision %d, want 1", revision)
+	}
+	deployment, _ := clientset.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "web:v1" {
+		t.Errorf("image after rollback = %s, want web:v1", image)
+	}
+	if _, ok := deployment.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+		t.Error("rollback copied the pod-template-hash label into the deployment")
+	}
+
+	if _, err := kube.RollbackDeployment(context.TODO(), "web", "default", 7); err == nil {
+		t.Error("RollbackDeployment() expected error for unknown revision")
+	}
+}
+
+func TestUpdateDeploymentImage(t *testing.T) {
+	opts := ImageUpdateOptions{RolloutTimeout: time.Second, SoakPeriod: 100 * time.Millisecond, PollInterval: 10 * time.Millisecond}
+
+	t.Run("healthy", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(newRolloutObjects(true)...)
+		kube := &KubeClient{Clientset: clientset}
+
+		result, err := kube.UpdateDeploymentImage(context.TODO(), "web", "default", "app", "web:v3", opts)
+		if err != nil {
+			t.Fatalf("UpdateDeploymentImage() error = %v", err)
+		}
+		if result.PreviousImage != "web:v2" || result.RolledBack {
+			t.Errorf("UpdateDeploymentImage() = %+v", result)
+		}
+		deployment, _ := clientset.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+		if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "web:v3" {
+			t.Errorf("image = %s, want web:v3", image)
+		}
+	})
+
+	t.Run("unready pod rolls back", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(newRolloutObjects(false)...)
+		kube := &KubeClient{Clientset: clientset}
+
+		result, err := kube.UpdateDeploymentImage(context.TODO(), "web", "default", "app", "web:v3", opts)
+		if err == nil || !strings.Contains(err.Error(), "rolled back") {
+			t.Fatalf("UpdateDeploymentImage() error = %v, want rollback", err)
+		}
+		if !result.RolledBack || result.RolledBackTo != 2 {
+			t.Errorf("UpdateDeploymentImage() = %+v, want rollback to revision 2", result)
+		}
+		deployment, _ := clientset.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+		if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "web:v2" {
+			t.Errorf("image after rollback = %s, want web:v2", image)
+		}
+	})
+
+	t.Run("terminating old pod is ignored", func(t *testing.T) {
+		old := newTestPod("web-old", false)
+		old.Labels = map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "hash-1"}
+		now := metav1.Now()
+		old.DeletionTimestamp = &now
+		old.Finalizers = []string{"kubernetes"}
+		stale := newTestPod("web-stale", false)
+		stale.Labels = map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "hash-1"}
+		kube := &KubeClient{Clientset: fake.NewSimpleClientset(append(newRolloutObjects(true), old, stale)...)}
+
+		result, err := kube.UpdateDeploymentImage(context.TODO(), "web", "default", "app", "web:v3", opts)
+		if err != nil {
+			t.Fatalf("UpdateDeploymentImage() error = %v", err)
+		}
+		if result.RolledBack {
+			t.Errorf("UpdateDeploymentImage() = %+v, want no rollback", result)
+		}
+	})
+
+	t.Run("no pods rolls back", func(t *testing.T) {
+		objects := newRolloutObjects(true)
+		kube := &KubeClient{Clientset: fake.NewSimpleClientset(objects[:len(objects)-1]...)}
+
+		result, err := kube.UpdateDeploymentImage(context.TODO(), "web", "default", "app", "web:v3", opts)
+		if err == nil || !result.RolledBack {
+			t.Fatalf("UpdateDeploymentImage() = %+v, %v, want rollback", result, err)
+		}
+		if !strings.Contains(result.Reason, "no pods") {
+			t.Errorf("UpdateDeploymentImage() reason = %s, want no pods", result.Reason)
+		}
+	})
+
+	t.Run("cancelled during soak", func(t *testing.T) {
+		kube := &KubeClient{Clientset: fake.NewSimpleClientset(newRolloutObjects(true)...)}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		long := opts
+		long.SoakPeriod = time.Minute
+		result, err := kube.UpdateDeploymentImage(ctx, "web", "default", "app", "web:v3", long)
+		if err == nil {
+			t.Fatal("UpdateDeploymentImage() expected error when cancelled during the soak")
+		}
+		if !strings.Contains(result.Reason, "soak interrupted") {
+			t.Errorf("UpdateDeploymentImage() reason = %s, want soak interrupted", result.Reason)
+		}
+	})
+
+	t.Run("unknown container", func(t *testing.T) {
+		kube := &KubeClient{Clientset: fake.NewSimpleClientset(newRolloutObjects(true)...)}
+		if _, err := kube.UpdateDeploymentImage(context.TODO(), "web", "default", "sidecar", "web:v3", opts); err == nil {
+			t.Error("UpdateDeploymentImage() expected error for unknown container")
+		}
+	})
+}